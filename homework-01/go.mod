@@ -0,0 +1,3 @@
+module github.com/pepilipep/fmi-ai-2021/homework-01
+
+go 1.16
@@ -0,0 +1,7 @@
+package main
+
+import "github.com/pepilipep/fmi-ai-2021/homework-01/puzzle"
+
+func main() {
+	puzzle.Main()
+}
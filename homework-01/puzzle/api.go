@@ -0,0 +1,305 @@
+// Package puzzle solves sliding tile puzzles of any rectangular size, with
+// IDA* and a few other searches. The command line in Main is built on it, and
+// so can other programs: New builds a puzzle from its rows of tiles and Solve
+// returns the moves along with the statistics of the search.
+package puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrUnsolvable is returned by Solve when the goal cannot be reached from the
+// board.
+var ErrUnsolvable = errors.New("puzzle is not solvable")
+
+// errNoPuzzle is the error of an *InputError for a PuzzleSolution that was
+// neither built by New nor read.
+var errNoPuzzle = errors.New("there is no puzzle, build it with New or read it first")
+
+// InputError is returned by New and the readers for input that does not
+// describe a puzzle, and by Solve for a puzzle that was never built. Field
+// names the part of the input at fault and Line, when known, the line it is
+// on.
+type InputError struct {
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *InputError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("invalid %s on line [%d]: %v", e.Field, e.Line, e.Err)
+	}
+	return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Goal describes the solved board. Without Board the tiles are in order and
+// the blank is in cell Blank, counted from 1 in row-major order as in the text
+// format; 0 and -1 both mean the last cell.
+type Goal struct {
+	Blank int
+	Board [][]int
+}
+
+// tilesOf flattens rows into row-major order, checking that they form a
+// rows x cols board holding every tile from 0 to rows*cols-1 exactly once.
+func tilesOf(field string, rows [][]int, cols int) ([]int, error) {
+	var tiles []int
+	seen := make([]bool, len(rows)*cols)
+	for i, row := range rows {
+		if len(row) != cols {
+			return nil, &InputError{Field: field, Err: fmt.Errorf("row [%d] has [%d] tiles instead of [%d]", i+1, len(row), cols)}
+		}
+		for _, tile := range row {
+			if tile < 0 || tile >= len(seen) || seen[tile] {
+				return nil, &InputError{Field: field, Err: fmt.Errorf("invalid or repeated tile: [%d]", tile)}
+			}
+			seen[tile] = true
+		}
+		tiles = append(tiles, row...)
+	}
+	return tiles, nil
+}
+
+// New builds the puzzle whose rows of tiles are board, 0 being the blank.
+func New(board [][]int, goal Goal) (*PuzzleSolution, error) {
+	if len(board) == 0 || len(board[0]) == 0 {
+		return nil, &InputError{Field: "board", Err: errors.New("the board is empty")}
+	}
+	rows, cols := len(board), len(board[0])
+	tiles, err := tilesOf("board", board, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PuzzleSolution{puzzleSpec: &puzzleSpec{n: rows*cols - 1, rows: rows, cols: cols}}
+	if p.board, err = newPackedBoard(rows * cols); err != nil {
+		return nil, &InputError{Field: "board", Err: err}
+	}
+	for idx, tile := range tiles {
+		p.board.set(idx, tile)
+		if tile == 0 {
+			p.currentZero = coordinateFromIndex(cols, idx)
+		}
+	}
+
+	if goal.Board == nil {
+		blank := goal.Blank
+		if blank == 0 || blank == -1 {
+			blank = rows * cols
+		}
+		if blank < 1 || blank > rows*cols {
+			return nil, &InputError{Field: "blank", Err: fmt.Errorf("blank position [%d] is outside the board", goal.Blank)}
+		}
+		p.goal = orderedGoal(rows, cols, blank-1)
+	} else {
+		if len(goal.Board) != rows {
+			return nil, &InputError{Field: "goal", Err: fmt.Errorf("[%d] rows instead of [%d]", len(goal.Board), rows)}
+		}
+		tiles, err := tilesOf("goal", goal.Board, cols)
+		if err != nil {
+			return nil, err
+		}
+		p.goal = make([]coordinate, rows*cols)
+		for idx, tile := range tiles {
+			p.goal[tile] = coordinateFromIndex(cols, idx)
+		}
+	}
+	p.zeroIndex = p.goal[0]
+	return p, nil
+}
+
+// Options select the search of Solve. The zero value runs IDA* with the
+// manhattan distance and no limits.
+type Options struct {
+	// Algorithm is one of ida, mm, astar, ees, table, constructive and sma.
+	Algorithm string
+	// Heuristic is one of manhattan, linear-conflict, misplaced,
+	// weighted-manhattan, pdb, walking-distance and walking-distance-lc.
+	Heuristic string
+	// Weight of the heuristic; solutions cost at most Weight times the
+	// optimum. Zero means one.
+	Weight   float64
+	Parallel bool
+	Torus    bool
+	// TileCosts is empty for unit costs, "tile" for k to move tile k, or the
+	// costs of tiles 1 to n as 3,1,2,...
+	TileCosts string
+	// Shorten cuts the loops out of the path of the constructive solver.
+	Shorten bool
+
+	// PatternDatabase is the partition of the pdb heuristic, e.g. 6-6-3.
+	// Its databases are loaded from PatternDatabaseDir, and built and stored
+	// there when missing; the table search does the same with TableDir.
+	// Either is required when it is used.
+	PatternDatabase    string
+	PatternDatabaseDir string
+	TableDir           string
+	// LoadedHeuristic, when set, is used instead of loading Heuristic. It
+	// has to be loaded for a puzzle of the same size and goal, and Torus.
+	LoadedHeuristic *Heuristic
+	// Log receives the progress of long steps, such as building a pattern
+	// database. Nil discards it.
+	Log io.Writer
+
+	TimeLimit time.Duration
+	MaxNodes  int64
+	// MaxMemory is in bytes.
+	MaxMemory uint64
+	// MaxStored is the number of nodes sma keeps in memory. Zero means a
+	// million.
+	MaxStored int
+}
+
+// Result is a solution found by Solve. Moves name the direction the tile
+// moves in: up, down, left or right.
+type Result struct {
+	Moves    []string
+	Cost     int
+	Bound    float64
+	Duration time.Duration
+	Stats    SearchStats
+}
+
+// Heuristic is a heuristic loaded for puzzles of one size, goal and geometry.
+// Loading one, such as a set of pattern databases, can take long, so it can
+// be loaded once by LoadHeuristic and then used by every Solve of such a
+// puzzle, concurrently as well.
+type Heuristic struct {
+	rows  int
+	cols  int
+	torus bool
+	goal  []coordinate
+	impl  heuristic
+}
+
+// Name is the name of the heuristic, as in Options.Heuristic.
+func (h *Heuristic) Name() string {
+	return h.impl.Name()
+}
+
+// fits reports whether h was loaded for puzzles like p.
+func (h *Heuristic) fits(p *PuzzleSolution) bool {
+	if h.rows != p.rows || h.cols != p.cols || h.torus != p.torus {
+		return false
+	}
+	for tile, c := range h.goal {
+		if p.goal[tile] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadHeuristic loads opts.Heuristic, manhattan when it is empty, for puzzles
+// of the size and goal of p and with opts.Torus. The pdb heuristic also uses
+// opts.PatternDatabase, opts.PatternDatabaseDir and opts.Log.
+func (p *PuzzleSolution) LoadHeuristic(opts Options) (*Heuristic, error) {
+	if p.puzzleSpec == nil {
+		return nil, &InputError{Field: "puzzle", Err: errNoPuzzle}
+	}
+	name := opts.Heuristic
+	if name == "" {
+		name = manhattanHeuristic{}.Name()
+	}
+	if name == patternDatabaseName && opts.PatternDatabaseDir == "" {
+		return nil, errors.New("the pdb heuristic needs a pattern database directory")
+	}
+
+	spec := *p.puzzleSpec
+	spec.torus = opts.Torus
+	root := &PuzzleSolution{puzzleSpec: &spec, board: p.board, currentZero: p.currentZero}
+	impl, err := root.loadHeuristic(heuristicOptions{
+		name:         name,
+		pdbPartition: opts.PatternDatabase,
+		pdbDir:       opts.PatternDatabaseDir,
+		log:          opts.Log,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Heuristic{rows: p.rows, cols: p.cols, torus: opts.Torus, goal: p.goal, impl: impl}, nil
+}
+
+// Solve searches for a solution of p as set by opts. It returns ErrUnsolvable
+// when there is none, and a *LimitError when a limit stops the search first.
+// The puzzle itself is left untouched, so it can be solved concurrently.
+func (p *PuzzleSolution) Solve(ctx context.Context, opts Options) (*Result, error) {
+	if p.puzzleSpec == nil {
+		return nil, &InputError{Field: "puzzle", Err: errNoPuzzle}
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = searchIDA
+	}
+	if opts.Weight == 0 {
+		opts.Weight = 1
+	}
+	if opts.MaxStored == 0 {
+		opts.MaxStored = defaultMaxStored
+	}
+	if err := validateSearch(opts.Algorithm, opts.Weight); err != nil {
+		return nil, err
+	}
+	if err := validateMaxStored(opts.MaxStored); err != nil {
+		return nil, err
+	}
+	if opts.Algorithm == searchTable && opts.TableDir == "" {
+		return nil, errors.New("the table search needs a state table directory")
+	}
+
+	spec := *p.puzzleSpec
+	spec.torus = opts.Torus
+	root := &PuzzleSolution{puzzleSpec: &spec, board: p.board.clone(), currentZero: p.currentZero}
+	if !root.IsSolvable() {
+		return nil, ErrUnsolvable
+	}
+	h := opts.LoadedHeuristic
+	if h == nil {
+		var err error
+		if h, err = p.LoadHeuristic(opts); err != nil {
+			return nil, err
+		}
+	} else if !h.fits(root) {
+		return nil, errors.New("the heuristic was loaded for another puzzle")
+	}
+	spec.heuristic = h.impl
+
+	res := &Result{}
+	solveOpts := solveOptions{
+		algorithm: opts.Algorithm,
+		parallel:  opts.Parallel,
+		weight:    opts.Weight,
+		tileCosts: opts.TileCosts,
+		torus:     opts.Torus,
+		tableDir:  opts.TableDir,
+		shorten:   opts.Shorten,
+		maxStored: opts.MaxStored,
+		log:       opts.Log,
+		limits:    searchLimits{timeLimit: opts.TimeLimit, maxNodes: opts.MaxNodes, maxMemory: opts.MaxMemory},
+		stats:     &res.Stats,
+	}
+	startTime := time.Now()
+	sol, err := root.solveContext(ctx, solveOpts)
+	res.Duration = time.Since(startTime)
+	if err != nil {
+		return nil, err
+	}
+	if sol == nil {
+		return nil, ErrUnsolvable
+	}
+
+	res.Cost, res.Bound = sol.Cost, sol.Bound
+	res.Moves = make([]string, len(sol.Operations))
+	for i, op := range sol.Operations {
+		res.Moves[i] = string(op)
+	}
+	return res, nil
+}
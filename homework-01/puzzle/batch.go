@@ -0,0 +1,384 @@
+package puzzle
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSolved     = "solved"
+	batchUnsolvable = "unsolvable"
+	batchLimit      = "limit"
+	batchNoSolution = "no solution"
+	batchError      = "error"
+)
+
+type batchInstance struct {
+	name   string
+	puzzle *PuzzleSolution
+	err    error
+}
+
+// readBatch reads the instances of a batch file. They are either in the
+// format Read expects, separated by blank lines, with an optional goal board
+// directly after the board, or one per line as in Korf's 100 15-puzzles:
+// an optional id followed by the tiles in row-major order, solved with the
+// blank in the first cell.
+func readBatch(reader *bufio.Reader) ([]batchInstance, error) {
+	var (
+		instances []batchInstance
+		paragraph []string
+		lineNo    int
+		start     int
+	)
+
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		if len(strings.Fields(paragraph[0])) > 2 {
+			for i, line := range paragraph {
+				instances = append(instances, readTileLine(len(instances)+1, start+i, line))
+			}
+		} else {
+			inst := batchInstance{name: strconv.Itoa(len(instances) + 1), puzzle: &PuzzleSolution{}}
+			r := &lineReader{reader: bufio.NewReader(strings.NewReader(strings.Join(paragraph, ""))), line: start - 1}
+			inst.err = inst.puzzle.readFrom(r)
+			instances = append(instances, inst)
+		}
+		paragraph = nil
+	}
+
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lineNo++
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if len(paragraph) == 0 {
+			start = lineNo
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+
+	return instances, nil
+}
+
+func readTileLine(idx int, lineNo int, line string) batchInstance {
+	inst := batchInstance{name: strconv.Itoa(idx), puzzle: &PuzzleSolution{}}
+
+	fields := strings.Fields(line)
+	side := int(math.Sqrt(float64(len(fields))))
+	if side*side != len(fields) {
+		inst.name = fields[0]
+		fields = fields[1:]
+		side = int(math.Sqrt(float64(len(fields))))
+	}
+	if side*side != len(fields) {
+		inst.err = &InputError{Line: lineNo, Field: "board", Err: fmt.Errorf("[%d] tiles do not form a square board", len(fields))}
+		return inst
+	}
+
+	p := inst.puzzle
+	p.puzzleSpec = &puzzleSpec{n: side*side - 1, rows: side, cols: side}
+	rows := make([]string, side)
+	for i := range rows {
+		rows[i] = strings.Join(fields[i*side:(i+1)*side], " ")
+	}
+	// Every row comes from the same line, which errors point at.
+	r := &lineReader{reader: bufio.NewReader(strings.NewReader(strings.Join(rows[1:], "\n")))}
+	tiles, err := p.readBoard(r, rows[0], "board")
+	if inputErr, ok := err.(*InputError); ok {
+		inputErr.Line = lineNo
+	}
+	if err == nil {
+		p.board, err = newPackedBoard(side * side)
+	}
+	if err != nil {
+		inst.err = err
+		return inst
+	}
+
+	for idx, tile := range tiles {
+		p.board.set(idx, tile)
+		if tile == 0 {
+			p.currentZero = coordinateFromIndex(side, idx)
+		}
+	}
+	p.goal = orderedGoal(side, side, 0)
+	p.zeroIndex = p.goal[0]
+	return inst
+}
+
+type batchResult struct {
+	Instance   string  `json:"instance"`
+	Status     string  `json:"status"`
+	Length     int     `json:"length"`
+	LowerBound int     `json:"lowerBound"`
+	Expanded   int64   `json:"expanded"`
+	Generated  int64   `json:"generated"`
+	Seconds    float64 `json:"seconds"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// heuristicCache shares the heuristic, most notably the pattern databases,
+// between the instances of a batch with the same size and goal.
+type heuristicCache struct {
+	mu         sync.Mutex
+	opts       heuristicOptions
+	heuristics map[string]heuristic
+}
+
+func (c *heuristicCache) load(p *PuzzleSolution) (heuristic, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprint(p.rows, p.cols, p.torus, p.goal)
+	if h, ok := c.heuristics[key]; ok {
+		return h, nil
+	}
+	h, err := p.loadHeuristic(c.opts)
+	if err != nil {
+		return nil, err
+	}
+	c.heuristics[key] = h
+	return h, nil
+}
+
+func solveBatchInstance(inst batchInstance, opts solveOptions, cache *heuristicCache) batchResult {
+	res := batchResult{Instance: inst.name}
+	fail := func(err error) batchResult {
+		res.Status = batchError
+		res.Error = err.Error()
+		return res
+	}
+
+	if inst.err != nil {
+		return fail(inst.err)
+	}
+	p := inst.puzzle
+	p.torus = opts.torus
+	if !p.IsSolvable() {
+		res.Status = batchUnsolvable
+		return res
+	}
+	h, err := cache.load(p)
+	if err != nil {
+		return fail(err)
+	}
+	p.heuristic = h
+
+	stats := &SearchStats{}
+	opts.stats = stats
+	startTime := time.Now()
+	sol, err := p.solveContext(context.Background(), opts)
+	res.Seconds = time.Since(startTime).Seconds()
+
+	total := stats.total()
+	res.Expanded, res.Generated, res.LowerBound = total.Expanded, total.Generated, total.LowerBound
+
+	var limitErr *LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		res.Status = batchLimit
+		res.Error = err.Error()
+	case err != nil:
+		return fail(err)
+	case sol == nil:
+		res.Status = batchNoSolution
+	default:
+		res.Status = batchSolved
+		res.Length = len(sol.Operations)
+	}
+	return res
+}
+
+// runBatch solves the instances on at most jobs goroutines, keeping the
+// results in the order of the instances.
+func runBatch(instances []batchInstance, jobs int, opts solveOptions, hOpts heuristicOptions) []batchResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	cache := &heuristicCache{opts: hOpts, heuristics: make(map[string]heuristic)}
+	results := make([]batchResult, len(instances))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				results[idx] = solveBatchInstance(instances[idx], opts, cache)
+			}
+		}()
+	}
+	for idx := range instances {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+type batchSummary struct {
+	Instances     int     `json:"instances"`
+	Solved        int     `json:"solved"`
+	Unsolvable    int     `json:"unsolvable"`
+	Limit         int     `json:"limit"`
+	Failed        int     `json:"failed"`
+	MeanLength    float64 `json:"meanLength"`
+	MinLength     int     `json:"minLength"`
+	MaxLength     int     `json:"maxLength"`
+	TotalExpanded int64   `json:"totalExpanded"`
+	MeanExpanded  float64 `json:"meanExpanded"`
+	TotalSeconds  float64 `json:"totalSeconds"`
+	MeanSeconds   float64 `json:"meanSeconds"`
+	MaxSeconds    float64 `json:"maxSeconds"`
+	WallSeconds   float64 `json:"wallSeconds"`
+}
+
+// summarize aggregates the lengths, nodes and times of the solved instances.
+func summarize(results []batchResult, wall time.Duration) batchSummary {
+	s := batchSummary{Instances: len(results), WallSeconds: wall.Seconds()}
+	totalLength := 0
+
+	for _, res := range results {
+		switch res.Status {
+		case batchSolved:
+		case batchUnsolvable:
+			s.Unsolvable++
+			continue
+		case batchLimit:
+			s.Limit++
+			continue
+		default:
+			s.Failed++
+			continue
+		}
+
+		if s.Solved == 0 || res.Length < s.MinLength {
+			s.MinLength = res.Length
+		}
+		if res.Length > s.MaxLength {
+			s.MaxLength = res.Length
+		}
+		if res.Seconds > s.MaxSeconds {
+			s.MaxSeconds = res.Seconds
+		}
+		s.Solved++
+		totalLength += res.Length
+		s.TotalExpanded += res.Expanded
+		s.TotalSeconds += res.Seconds
+	}
+
+	if s.Solved > 0 {
+		s.MeanLength = float64(totalLength) / float64(s.Solved)
+		s.MeanExpanded = float64(s.TotalExpanded) / float64(s.Solved)
+		s.MeanSeconds = s.TotalSeconds / float64(s.Solved)
+	}
+	return s
+}
+
+func (s batchSummary) Print(w io.Writer) {
+	fmt.Fprintf(w, "instances: %d, solved: %d, unsolvable: %d, limit: %d, errors: %d\n",
+		s.Instances, s.Solved, s.Unsolvable, s.Limit, s.Failed)
+	if s.Solved > 0 {
+		fmt.Fprintf(w, "length: mean %.3f, min %d, max %d\n", s.MeanLength, s.MinLength, s.MaxLength)
+		fmt.Fprintf(w, "expanded: total %d, mean %.3f\n", s.TotalExpanded, s.MeanExpanded)
+		fmt.Fprintf(w, "time: total %.3f, mean %.3f, max %.3f\n", s.TotalSeconds, s.MeanSeconds, s.MaxSeconds)
+	}
+	fmt.Fprintf(w, "wall time: %.3f\n", s.WallSeconds)
+}
+
+func writeBatchCSV(w io.Writer, results []batchResult) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"instance", "status", "length", "lower_bound", "expanded", "generated", "seconds", "error"})
+	for _, res := range results {
+		out.Write([]string{
+			res.Instance,
+			res.Status,
+			strconv.Itoa(res.Length),
+			strconv.Itoa(res.LowerBound),
+			strconv.FormatInt(res.Expanded, 10),
+			strconv.FormatInt(res.Generated, 10),
+			strconv.FormatFloat(res.Seconds, 'f', 3, 64),
+			res.Error,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func writeBatchJSON(w io.Writer, results []batchResult, summary batchSummary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Results []batchResult `json:"results"`
+		Summary batchSummary  `json:"summary"`
+	}{results, summary})
+}
+
+// solveBatch solves every instance of the file at path and writes a report, as
+// JSON when reportPath ends in .json and as CSV otherwise, to reportPath or
+// to stdout when it is empty. The summary goes to stdout, or to stderr when
+// the report is already there.
+func solveBatch(path string, reportPath string, jobs int, opts solveOptions, hOpts heuristicOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	instances, err := readBatch(bufio.NewReader(f))
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	results := runBatch(instances, jobs, opts, hOpts)
+	summary := summarize(results, time.Since(startTime))
+
+	report, summaryOut := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if reportPath != "" {
+		out, err := os.Create(reportPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		report, summaryOut = out, os.Stdout
+	}
+
+	if strings.HasSuffix(reportPath, ".json") {
+		err = writeBatchJSON(report, results, summary)
+	} else {
+		err = writeBatchCSV(report, results)
+	}
+	if err != nil {
+		return err
+	}
+	summary.Print(summaryOut)
+	return nil
+}
@@ -0,0 +1,218 @@
+package puzzle
+
+type frontierEntry struct {
+	node *PuzzleSolution
+	open bool
+}
+
+type bucketEntry struct {
+	entry *frontierEntry
+	node  *PuzzleSolution
+}
+
+// searchFrontier is the open and closed list of a best-first search, such as
+// one side of the bidirectional search. Open nodes are kept in buckets by
+// their priority, while only the counts are needed for the smallest f- and
+// g-values used by the stopping rules.
+type searchFrontier struct {
+	priority func(*PuzzleSolution) int
+	nodes    map[boardKey]*frontierEntry
+	buckets  [][]bucketEntry
+	fCount   []int
+	gCount   []int
+	open     int
+	minPrior int
+}
+
+func newSearchFrontier(root *PuzzleSolution, priority func(*PuzzleSolution) int) *searchFrontier {
+	s := &searchFrontier{priority: priority, nodes: make(map[boardKey]*frontierEntry)}
+	s.push(root)
+	return s
+}
+
+func mmPriority(node *PuzzleSolution) int {
+	if 2*node.cost > node.priority() {
+		return 2 * node.cost
+	}
+	return node.priority()
+}
+
+func grow(counts []int, idx int) []int {
+	for len(counts) <= idx {
+		counts = append(counts, 0)
+	}
+	return counts
+}
+
+func firstNonEmpty(counts []int) int {
+	for idx, count := range counts {
+		if count > 0 {
+			return idx
+		}
+	}
+	return unreachable
+}
+
+func (s *searchFrontier) push(node *PuzzleSolution) {
+	key := node.board.key()
+	entry, ok := s.nodes[key]
+	if ok && entry.open {
+		s.forget(entry.node)
+	}
+	if !ok {
+		entry = &frontierEntry{}
+		s.nodes[key] = entry
+	}
+	entry.node = node
+	entry.open = true
+
+	pr := s.priority(node)
+	for len(s.buckets) <= pr {
+		s.buckets = append(s.buckets, nil)
+	}
+	s.buckets[pr] = append(s.buckets[pr], bucketEntry{entry: entry, node: node})
+	if pr < s.minPrior {
+		s.minPrior = pr
+	}
+
+	s.fCount = grow(s.fCount, node.priority())
+	s.fCount[node.priority()]++
+	s.gCount = grow(s.gCount, node.cost)
+	s.gCount[node.cost]++
+	s.open++
+}
+
+func (s *searchFrontier) forget(node *PuzzleSolution) {
+	s.fCount[node.priority()]--
+	s.gCount[node.cost]--
+	s.open--
+}
+
+// peekPriority drops stale bucket entries and returns the smallest priority
+// among the open nodes.
+func (s *searchFrontier) peekPriority() int {
+	for ; s.minPrior < len(s.buckets); s.minPrior++ {
+		bucket := s.buckets[s.minPrior]
+		for len(bucket) > 0 {
+			top := bucket[len(bucket)-1]
+			if top.entry.open && top.entry.node == top.node {
+				s.buckets[s.minPrior] = bucket
+				return s.minPrior
+			}
+			bucket = bucket[:len(bucket)-1]
+		}
+		s.buckets[s.minPrior] = bucket
+	}
+	return unreachable
+}
+
+func (s *searchFrontier) pop() *PuzzleSolution {
+	s.peekPriority()
+	bucket := s.buckets[s.minPrior]
+	top := bucket[len(bucket)-1]
+	s.buckets[s.minPrior] = bucket[:len(bucket)-1]
+
+	top.entry.open = false
+	s.forget(top.node)
+	return top.node
+}
+
+// solveBidirectional is the MM algorithm (Holte et al., 2016): both sides
+// expand nodes in order of max(f, 2g), so they are guaranteed to meet in the
+// middle, and it stops once the best path found so far is provably optimal.
+func (p *PuzzleSolution) solveBidirectional(budget *searchBudget) (*solution, error) {
+	// The backward search solves the reversed puzzle, whose goal is the start
+	// board. Pattern databases are tied to their goal, so it falls back to
+	// the manhattan distance in that case, while the walking distance builds
+	// its tables again for the line of the new blank.
+	backwardSpec := *p.puzzleSpec
+	backwardSpec.zeroIndex = p.currentZero
+	backwardSpec.goal = make([]coordinate, p.n+1)
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			backwardSpec.goal[p.tileAt(i, j)] = coordinate{x: i, y: j}
+		}
+	}
+	goal := p.goalBoard()
+	goal.puzzleSpec = &backwardSpec
+	switch h := p.heuristic.(type) {
+	case *patternDatabaseHeuristic:
+		backwardSpec.heuristic = manhattanHeuristic{}
+	case *walkingDistanceHeuristic:
+		var err error
+		if backwardSpec.heuristic, err = goal.loadWalkingDistance(h.linearConflict); err != nil {
+			return nil, err
+		}
+	}
+
+	forward := newSearchFrontier(p.searchRoot(), mmPriority)
+	backward := newSearchFrontier(goal, mmPriority)
+
+	best := unreachable
+	var meetForward, meetBackward *PuzzleSolution
+
+	if entry, ok := backward.nodes[p.board.key()]; ok {
+		best, meetForward, meetBackward = 0, forward.nodes[p.board.key()].node, entry.node
+	}
+
+	for forward.open > 0 && backward.open > 0 {
+		prForward, prBackward := forward.peekPriority(), backward.peekPriority()
+		prMin := prForward
+		if prBackward < prMin {
+			prMin = prBackward
+		}
+		bound := prMin
+		for _, lower := range []int{
+			firstNonEmpty(forward.fCount),
+			firstNonEmpty(backward.fCount),
+			firstNonEmpty(forward.gCount) + firstNonEmpty(backward.gCount) + p.minTileCost(),
+		} {
+			if lower > bound {
+				bound = lower
+			}
+		}
+		if best <= bound {
+			break
+		}
+		budget.prove(bound, prMin)
+		if err := budget.expand(budget.ctx); err != nil {
+			return nil, err
+		}
+
+		side, other := forward, backward
+		if prBackward < prForward {
+			side, other = backward, forward
+		}
+
+		node := side.pop()
+		children := node.Neighbors()
+		budget.generate(len(children), node.depth+1)
+		for _, child := range children {
+			key := child.board.key()
+			if entry, ok := side.nodes[key]; ok && entry.node.cost <= child.cost {
+				continue
+			}
+			side.push(child)
+
+			if entry, ok := other.nodes[key]; ok && child.cost+entry.node.cost < best {
+				best = child.cost + entry.node.cost
+				meetForward, meetBackward = child, entry.node
+				if side == backward {
+					meetForward, meetBackward = entry.node, child
+				}
+			}
+		}
+		budget.frontier(forward.open + backward.open)
+	}
+
+	if meetForward == nil {
+		return nil, nil
+	}
+
+	ops := meetForward.operations()
+	back := meetBackward.operations()
+	for i := len(back) - 1; i >= 0; i-- {
+		ops = append(ops, back[i].opposite())
+	}
+	return &solution{Operations: ops, Cost: best}, nil
+}
@@ -0,0 +1,77 @@
+package puzzle
+
+import "fmt"
+
+const (
+	smallBoardCells = 16
+	maxBoardCells   = 1 << 16
+)
+
+// packedBoard keeps 4 bits per cell in a single word for boards of up to 16
+// cells. Larger boards fall back to two bytes per cell.
+type packedBoard struct {
+	small uint64
+	wide  []uint16
+}
+
+// boardKey is a comparable form of packedBoard, usable as a map key.
+type boardKey struct {
+	small uint64
+	wide  string
+}
+
+func newPackedBoard(cells int) (packedBoard, error) {
+	if cells > maxBoardCells {
+		return packedBoard{}, fmt.Errorf("boards with more than [%d] cells are not supported", maxBoardCells)
+	}
+	if cells > smallBoardCells {
+		return packedBoard{wide: make([]uint16, cells)}, nil
+	}
+	return packedBoard{}, nil
+}
+
+func (b *packedBoard) get(idx int) int {
+	if b.wide != nil {
+		return int(b.wide[idx])
+	}
+	return int(b.small>>(4*uint(idx))) & 0xf
+}
+
+func (b *packedBoard) set(idx int, tile int) {
+	if b.wide != nil {
+		b.wide[idx] = uint16(tile)
+		return
+	}
+	shift := 4 * uint(idx)
+	b.small = b.small&^(0xf<<shift) | uint64(tile)<<shift
+}
+
+func (b *packedBoard) swap(i, j int) {
+	if b.wide != nil {
+		b.wide[i], b.wide[j] = b.wide[j], b.wide[i]
+		return
+	}
+	ti, tj := b.get(i), b.get(j)
+	b.set(i, tj)
+	b.set(j, ti)
+}
+
+func (b *packedBoard) clone() packedBoard {
+	if b.wide == nil {
+		return packedBoard{small: b.small}
+	}
+	wide := make([]uint16, len(b.wide))
+	copy(wide, b.wide)
+	return packedBoard{wide: wide}
+}
+
+func (b *packedBoard) key() boardKey {
+	if b.wide == nil {
+		return boardKey{small: b.small}
+	}
+	wide := make([]byte, 2*len(b.wide))
+	for i, tile := range b.wide {
+		wide[2*i], wide[2*i+1] = byte(tile), byte(tile>>8)
+	}
+	return boardKey{wide: string(wide)}
+}
@@ -0,0 +1,214 @@
+package puzzle
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Main runs the command line: it parses the flags, reads the puzzle from
+// stdin unless a flag says otherwise and prints the solution.
+func Main() {
+	heuristicName := flag.String("heuristic", "manhattan", "heuristic guiding the search: "+strings.Join(heuristicNames(), ", "))
+	pdbPartition := flag.String("pdb", "", "pattern database partition as group sizes (6-6-3) or tiles (1,2,5,6/3,4,7,8); defaults by puzzle size")
+	pdbDir := flag.String("pdb-dir", "pdb", "directory the pattern databases are loaded from and stored to")
+	algorithm := flag.String("search", searchIDA, "search algorithm: "+strings.Join(searchAlgorithms, ", "))
+	parallel := flag.Bool("parallel", false, "split the IDA* search tree between GOMAXPROCS workers")
+	weight := flag.Float64("weight", 1, "heuristic weight w of ida, astar, ees and sma; solutions cost at most w times the optimum")
+	torus := flag.Bool("torus", false, "let the blank leave the board on one edge and come back on the opposite one")
+	tileCosts := flag.String("tile-costs", "", "cost of moving each tile: empty for 1, \"tile\" for k to move tile k, or costs of tiles 1 to n as 3,1,2,...")
+	timeLimit := flag.Duration("time-limit", 0, "stop the search after this long, e.g. 30s; 0 means no limit")
+	maxNodes := flag.Int64("max-nodes", 0, "stop the search after expanding this many nodes; 0 means no limit")
+	maxMemory := flag.Uint64("max-memory", 0, "stop the search once the heap grows over this many MiB; 0 means no limit")
+	maxStored := flag.Int("max-stored", defaultMaxStored, "nodes the sma search keeps in memory at most, dropping the worst ones beyond")
+	statsTable := flag.Bool("stats", false, "print the statistics of every search iteration to stderr")
+	tracePath := flag.String("trace", "", "write the statistics of every search iteration to this JSON file")
+	verifyPath := flag.String("verify", "", "check the move list in this file against the puzzle instead of solving it")
+	generateMode := flag.String("generate", "", "print random solvable puzzles instead of solving one: "+strings.Join(generateModes, ", "))
+	rows := flag.Int("rows", 3, "rows of the generated puzzles, images and built state tables")
+	cols := flag.Int("cols", 3, "columns of the generated puzzles, images and built state tables")
+	count := flag.Int("count", 1, "number of generated puzzles")
+	walkLength := flag.Int("walk-length", 50, "number of random moves from the goal in walk mode and for images")
+	seed := flag.Int64("seed", 1, "seed of the generator")
+	minLength := flag.Int("min-length", 0, "keep only generated puzzles with an optimal solution at least this long")
+	maxLength := flag.Int("max-length", 0, "keep only generated puzzles with an optimal solution at most this long; 0 means no limit")
+	batchPath := flag.String("batch", "", "solve every puzzle in this file instead of one from stdin")
+	jobs := flag.Int("jobs", 1, "number of puzzles of a batch solved at the same time")
+	reportPath := flag.String("report", "", "write the batch report to this file, as JSON if it ends in .json and CSV otherwise")
+	imagePath := flag.String("image", "", "cut this PNG or JPEG image into rows x cols tiles, scramble and solve it")
+	gifPath := flag.String("gif", "solution.gif", "where the animated solution of an image is written")
+	optimalMode := flag.String("optimal-solutions", "", "instead of one solution, find every optimal one: "+strings.Join(enumerateModes, ", "))
+	solutionCap := flag.Int("solution-cap", 1000, "stop enumerating optimal solutions after this many; 0 means no cap")
+	tableDir := flag.String("table-dir", "tables", "directory the complete state tables of small puzzles are loaded from and stored to")
+	buildTable := flag.Bool("build-table", false, "build the complete state table of the rows x cols puzzle and exit")
+	checkTable := flag.Bool("check-table", false, "compare the length of the solution with the optimal one from the state table")
+	shorten := flag.Bool("shorten", false, "cut the loops out of the path of the constructive solver")
+	format := flag.String("format", formatText, "format of the puzzle read from stdin and of the solution: text, or json for a document with board, blank, goal and options")
+	agentMode := flag.String("agent", "", "solve in real time, one move per lookahead, over repeated trials: "+strings.Join(agentModes, ", "))
+	lookahead := flag.Int("lookahead", 1, "number of moves the agent looks ahead before committing to one")
+	trials := flag.Int("trials", 20, "maximal number of agent trials, which stop earlier once the agent learns nothing new")
+	maxMoves := flag.Int("max-moves", 100000, "moves after which an agent trial gives up")
+	flag.Parse()
+
+	if err := validateSearch(*algorithm, *weight); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+	if err := validateMaxStored(*maxStored); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+	if err := validateGenerate(*generateMode); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+	if err := validateEnumerate(*optimalMode, *algorithm, *weight); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+
+	if err := validateAgent(*agentMode, *lookahead); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+	if err := validateFormat(*format); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+
+	if *format == formatJSON {
+		defaults := jsonOptions{
+			Algorithm: *algorithm,
+			Heuristic: *heuristicName,
+			Weight:    *weight,
+			Parallel:  *parallel,
+			Torus:     *torus,
+			TileCosts: *tileCosts,
+			Shorten:   *shorten,
+			PDB:       *pdbPartition,
+			PDBDir:    *pdbDir,
+			TableDir:  *tableDir,
+			TimeLimit: timeLimit.String(),
+			MaxNodes:  *maxNodes,
+			MaxMemory: *maxMemory,
+			MaxStored: *maxStored,
+		}
+		if err := runJSON(os.Stdin, os.Stdout, os.Stderr, defaults); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	hOpts := heuristicOptions{name: *heuristicName, pdbPartition: *pdbPartition, pdbDir: *pdbDir, log: os.Stderr}
+	opts := solveOptions{
+		algorithm: *algorithm,
+		parallel:  *parallel,
+		weight:    *weight,
+		tileCosts: *tileCosts,
+		torus:     *torus,
+		tableDir:  *tableDir,
+		shorten:   *shorten,
+		maxStored: *maxStored,
+		log:       os.Stderr,
+		limits:    searchLimits{timeLimit: *timeLimit, maxNodes: *maxNodes, maxMemory: *maxMemory << 20},
+	}
+
+	if *buildTable {
+		if err := BuildStateTable(*rows, *cols, *torus, *tableDir); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *batchPath != "" {
+		if err := solveBatch(*batchPath, *reportPath, *jobs, opts, hOpts); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *imagePath != "" {
+		genOpts := generateOptions{walkLength: *walkLength, seed: *seed}
+		if err := solvePicture(*imagePath, *gifPath, *rows, *cols, genOpts, opts, hOpts); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	puzzleSolution := PuzzleSolution{}
+
+	if *generateMode != "" {
+		if err := puzzleSolution.newEmptyPuzzle(*rows, *cols); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+	} else if err := puzzleSolution.Read(); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+	puzzleSolution.torus = *torus
+
+	if *verifyPath != "" {
+		sol, err := readSolutionFile(*verifyPath)
+		if err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		res := puzzleSolution.verify(sol)
+		res.Print()
+		if !res.ok() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !puzzleSolution.IsSolvable() {
+		fmt.Println("puzzle is not solvable...")
+		os.Exit(1)
+	}
+
+	var err error
+	if puzzleSolution.heuristic, err = puzzleSolution.loadHeuristic(hOpts); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+
+	if *generateMode != "" {
+		genOpts := generateOptions{
+			mode:       *generateMode,
+			count:      *count,
+			walkLength: *walkLength,
+			seed:       *seed,
+			minLength:  *minLength,
+			maxLength:  *maxLength,
+		}
+		if err := puzzleSolution.generate(os.Stdout, genOpts, opts); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *agentMode != "" {
+		agentOpts := agentOptions{mode: *agentMode, lookahead: *lookahead, trials: *trials, maxMoves: *maxMoves}
+		if err := puzzleSolution.runAgent(os.Stdout, agentOpts, *tileCosts); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *optimalMode != "" {
+		if err := puzzleSolution.solveAllAndPrint(opts, *optimalMode, *solutionCap); err != nil {
+			fmt.Printf("error found: [%v]", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := puzzleSolution.solveAndPrint(opts, *statsTable, *tracePath, *checkTable); err != nil {
+		fmt.Printf("error found: [%v]", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,380 @@
+package puzzle
+
+import "fmt"
+
+// constructor solves a board the way people do by hand: the rows from the
+// top down to the last two, then the columns of those two rows from the left
+// down to the last two, and finally the remaining 2x2 square. Tiles are placed
+// one at a time and the cells already solved are locked. It works on a plain
+// copy of the board and records the moves.
+type constructor struct {
+	rows   int
+	cols   int
+	cells  []int
+	where  []int
+	target []int
+	locked []bool
+	ops    []operation
+}
+
+func newConstructor(p *PuzzleSolution) *constructor {
+	c := &constructor{
+		rows:   p.rows,
+		cols:   p.cols,
+		cells:  make([]int, p.rows*p.cols),
+		where:  make([]int, p.rows*p.cols),
+		target: make([]int, p.rows*p.cols),
+		locked: make([]bool, p.rows*p.cols),
+	}
+	for idx := range c.cells {
+		c.cells[idx] = p.board.get(idx)
+		c.where[c.cells[idx]] = idx
+	}
+	for tile, pos := range p.goal {
+		c.target[tile] = pos.toIndex(p.cols)
+	}
+	return c
+}
+
+func (c *constructor) cell(x, y int) int {
+	return x*c.cols + y
+}
+
+func (c *constructor) neighbors(idx int) []int {
+	var res []int
+	x, y := idx/c.cols, idx%c.cols
+	if x > 0 {
+		res = append(res, idx-c.cols)
+	}
+	if y > 0 {
+		res = append(res, idx-1)
+	}
+	if y < c.cols-1 {
+		res = append(res, idx+1)
+	}
+	if x < c.rows-1 {
+		res = append(res, idx+c.cols)
+	}
+	return res
+}
+
+// slide moves the blank to the adjacent cell to.
+func (c *constructor) slide(to int) {
+	blank := c.where[0]
+	shift := coordinate{x: to/c.cols - blank/c.cols, y: to%c.cols - blank%c.cols}
+	for _, op := range operations {
+		if op.blankShift() == shift {
+			c.ops = append(c.ops, op)
+			break
+		}
+	}
+
+	tile := c.cells[to]
+	c.cells[blank], c.where[tile] = tile, blank
+	c.cells[to], c.where[0] = 0, to
+}
+
+// path finds the shortest way from a cell to the first one accepted by goal
+// through unlocked cells other than avoid. The start is left out.
+func (c *constructor) path(from int, goal func(idx int) bool, avoid int) ([]int, bool) {
+	parent := make([]int, len(c.cells))
+	for idx := range parent {
+		parent[idx] = -1
+	}
+	parent[from] = from
+	queue := []int{from}
+
+	for len(queue) > 0 {
+		idx := queue[0]
+		queue = queue[1:]
+		if goal(idx) {
+			var res []int
+			for ; idx != from; idx = parent[idx] {
+				res = append([]int{idx}, res...)
+			}
+			return res, true
+		}
+		for _, next := range c.neighbors(idx) {
+			if parent[next] == -1 && !c.locked[next] && next != avoid {
+				parent[next] = idx
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil, false
+}
+
+func (c *constructor) moveBlank(goal func(idx int) bool, avoid int) bool {
+	steps, ok := c.path(c.where[0], goal, avoid)
+	for _, idx := range steps {
+		c.slide(idx)
+	}
+	return ok
+}
+
+// moveTile walks tile to the cell to, one cell at a time, bringing the blank
+// in front of it around the tile before each step. The tile never crosses
+// the cell avoid.
+func (c *constructor) moveTile(tile, to int, avoid int) bool {
+	steps, ok := c.path(c.where[tile], func(idx int) bool { return idx == to }, avoid)
+	if !ok {
+		return false
+	}
+	for _, next := range steps {
+		at := func(idx int) bool { return idx == next }
+		if !c.moveBlank(at, c.where[tile]) {
+			return false
+		}
+		c.slide(c.where[tile])
+	}
+	return true
+}
+
+func (c *constructor) place(tile int) bool {
+	if !c.moveTile(tile, c.target[tile], -1) {
+		return false
+	}
+	c.locked[c.target[tile]] = true
+	return true
+}
+
+// placePair places the last two tiles a and b of a row or a column, which
+// cannot go in one after the other. Once a is in place, the target of b is
+// a dead end, so b is parked in the window of the pair instead, and the two
+// are then brought home by a search over the window alone.
+func (c *constructor) placePair(a, b int, window []int, park int) bool {
+	first, second := c.target[a], c.target[b]
+	if c.where[a] == first && c.where[b] == second {
+		c.locked[first], c.locked[second] = true, true
+		return true
+	}
+	if !c.place(a) {
+		return false
+	}
+
+	if c.where[0] == second {
+		for _, idx := range c.neighbors(second) {
+			if c.where[b] == idx {
+				c.slide(idx)
+			}
+		}
+	}
+	if c.where[b] == second {
+		c.locked[second] = true
+		return true
+	}
+	if !c.moveTile(b, park, second) {
+		return false
+	}
+
+	inWindow := func(idx int) bool {
+		for _, w := range window {
+			if w == idx {
+				return true
+			}
+		}
+		return false
+	}
+	if !c.moveBlank(inWindow, c.where[b]) {
+		return false
+	}
+	c.locked[first] = false
+	if !c.solveWindow(window, []int{a, b}) {
+		return false
+	}
+	c.locked[first], c.locked[second] = true, true
+	return true
+}
+
+// solveWindow moves the blank within the cells of window only, until every
+// tile of tiles, all of which must be in the window with the blank, reaches
+// its target. The other tiles of the window can end up anywhere.
+func (c *constructor) solveWindow(window []int, tiles []int) bool {
+	slot := make(map[int]int)
+	for i, idx := range window {
+		slot[idx] = i
+	}
+
+	// A state packs the slots of the blank and of tiles in base len(window).
+	encode := func(pos []int) int {
+		state := 0
+		for i := len(pos) - 1; i >= 0; i-- {
+			state = state*len(window) + pos[i]
+		}
+		return state
+	}
+	decode := func(state int) []int {
+		pos := make([]int, len(tiles)+1)
+		for i := range pos {
+			pos[i] = state % len(window)
+			state /= len(window)
+		}
+		return pos
+	}
+
+	start := make([]int, len(tiles)+1)
+	goal := make([]int, len(tiles)+1)
+	for i, tile := range append([]int{0}, tiles...) {
+		s, ok := slot[c.where[tile]]
+		if !ok {
+			return false
+		}
+		start[i] = s
+		goal[i] = slot[c.target[tile]]
+	}
+
+	done := func(pos []int) bool {
+		for i := 1; i < len(pos); i++ {
+			if pos[i] != goal[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	parent := map[int]int{encode(start): -1}
+	queue := []int{encode(start)}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		pos := decode(state)
+
+		if done(pos) {
+			var blanks []int
+			for ; parent[state] != -1; state = parent[state] {
+				blanks = append([]int{window[decode(state)[0]]}, blanks...)
+			}
+			for _, idx := range blanks {
+				c.slide(idx)
+			}
+			return true
+		}
+
+		for _, next := range c.neighbors(window[pos[0]]) {
+			to, ok := slot[next]
+			if !ok {
+				continue
+			}
+			moved := append([]int(nil), pos...)
+			for i := 1; i < len(moved); i++ {
+				if moved[i] == to {
+					moved[i] = pos[0]
+				}
+			}
+			moved[0] = to
+			key := encode(moved)
+			if _, ok := parent[key]; !ok {
+				parent[key] = state
+				queue = append(queue, key)
+			}
+		}
+	}
+	return false
+}
+
+func (c *constructor) solve() bool {
+	if c.rows < 2 || c.cols < 2 {
+		return c.moveBlank(func(idx int) bool { return idx == c.target[0] }, -1)
+	}
+
+	tileFor := func(x, y int) int {
+		return c.goalTile(c.cell(x, y))
+	}
+	for x := 0; x < c.rows-2; x++ {
+		for y := 0; y < c.cols-2; y++ {
+			if !c.place(tileFor(x, y)) {
+				return false
+			}
+		}
+		left, right := c.cols-2, c.cols-1
+		window := []int{c.cell(x, left), c.cell(x, right), c.cell(x+1, left), c.cell(x+1, right), c.cell(x+2, left), c.cell(x+2, right)}
+		if !c.placePair(tileFor(x, left), tileFor(x, right), window, c.cell(x+2, left)) {
+			return false
+		}
+	}
+
+	top, bottom := c.rows-2, c.rows-1
+	for y := 0; y < c.cols-2; y++ {
+		window := []int{c.cell(top, y), c.cell(bottom, y), c.cell(top, y+1), c.cell(bottom, y+1), c.cell(top, y+2), c.cell(bottom, y+2)}
+		if !c.placePair(tileFor(top, y), tileFor(bottom, y), window, c.cell(top, y+2)) {
+			return false
+		}
+	}
+
+	left, right := c.cols-2, c.cols-1
+	square := []int{c.cell(top, left), c.cell(top, right), c.cell(bottom, left), c.cell(bottom, right)}
+	return c.solveWindow(square, []int{tileFor(top, left), tileFor(top, right), tileFor(bottom, left)})
+}
+
+func (c *constructor) goalTile(idx int) int {
+	for tile, at := range c.target {
+		if at == idx {
+			return tile
+		}
+	}
+	return -1
+}
+
+// solveConstructive solves p without any search, in a number of moves
+// polynomial in the size of the board but usually far from optimal. The
+// goal is first turned into one with the blank in the last cell, by walking
+// the blank there, so that the 2x2 square left at the end holds the blank;
+// that walk is undone after the square. With shorten, the loops of the path
+// are cut out.
+func (p *PuzzleSolution) solveConstructive(shorten bool) (*solution, error) {
+	if p.torus {
+		return nil, fmt.Errorf("the constructive solver does not move tiles around the edges of a torus")
+	}
+
+	goal := p.goalBoard()
+	walk := newConstructor(goal)
+	walk.moveBlank(func(idx int) bool { return idx == len(walk.cells)-1 }, -1)
+
+	c := newConstructor(p)
+	copy(c.target, walk.where)
+	if !c.solve() {
+		return nil, fmt.Errorf("the constructive solver got stuck, is the puzzle solvable?")
+	}
+	ops := c.ops
+	for i := len(walk.ops) - 1; i >= 0; i-- {
+		ops = append(ops, walk.ops[i].opposite())
+	}
+	if shorten {
+		ops = p.shorten(ops)
+	}
+
+	sol := &solution{Operations: ops}
+	board := p.searchRoot()
+	for _, op := range ops {
+		tile, _ := board.apply(op)
+		sol.Cost += p.tileCost(tile)
+	}
+	if !board.isGoalBoard() {
+		return nil, fmt.Errorf("the constructive solver did not reach the goal, is the puzzle solvable?")
+	}
+	return sol, nil
+}
+
+// shorten cuts out every part of ops that comes back to a board seen before.
+func (p *PuzzleSolution) shorten(ops []operation) []operation {
+	board := p.searchRoot()
+	states := []boardKey{board.board.key()}
+	seen := map[boardKey]int{states[0]: 0}
+	var kept []operation
+
+	for _, op := range ops {
+		board.apply(op)
+		key := board.board.key()
+		if at, ok := seen[key]; ok {
+			for _, dropped := range states[at+1:] {
+				delete(seen, dropped)
+			}
+			states, kept = states[:at+1], kept[:at]
+			continue
+		}
+		seen[key] = len(states)
+		states = append(states, key)
+		kept = append(kept, op)
+	}
+	return kept
+}
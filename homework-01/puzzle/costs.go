@@ -0,0 +1,63 @@
+package puzzle
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	costPrefix = "cost:"
+
+	// tileCostsByNumber makes moving tile k cost k.
+	tileCostsByNumber = "tile"
+)
+
+func (p *puzzleSpec) tileCost(tile int) int {
+	if p.costs == nil {
+		return 1
+	}
+	return p.costs[tile]
+}
+
+func (p *puzzleSpec) minTileCost() int {
+	if p.costs == nil {
+		return 1
+	}
+	least := p.costs[1]
+	for _, cost := range p.costs[1:] {
+		if cost < least {
+			least = cost
+		}
+	}
+	return least
+}
+
+// setTileCosts sets how much moving each tile costs: one for every tile when
+// spec is empty, k for tile k when it is "tile", or a comma separated list of
+// costs for tiles 1 to n. Costs start at one, so every heuristic counting
+// moves stays admissible.
+func (p *puzzleSpec) setTileCosts(spec string) error {
+	switch spec {
+	case "":
+		p.costs = nil
+		return nil
+	case tileCostsByNumber:
+		p.costs = make([]int, p.n+1)
+		for tile := range p.costs {
+			p.costs[tile] = tile
+		}
+		return nil
+	}
+
+	costs, err := retrieveNumbers(strings.ReplaceAll(spec, ",", " "), p.n)
+	if err != nil {
+		return err
+	}
+	for i, cost := range costs {
+		if cost < 1 {
+			return fmt.Errorf("cost of tile [%d] must be at least 1, found: [%d]", i+1, cost)
+		}
+	}
+	p.costs = append([]int{0}, costs...)
+	return nil
+}
@@ -0,0 +1,130 @@
+package puzzle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	enumerateCount = "count"
+	enumerateList  = "list"
+)
+
+var enumerateModes = []string{enumerateCount, enumerateList}
+
+// optimalSolutions are the distinct optimal move sequences of a puzzle, or
+// only their number when they were not kept. Capped is set when the search
+// stopped at the cap, so there may be more.
+type optimalSolutions struct {
+	Cost      int
+	Length    int
+	Count     int
+	Capped    bool
+	Solutions []*solution
+}
+
+func validateEnumerate(mode string, algorithm string, weight float64) error {
+	if mode == "" {
+		return nil
+	}
+	if mode != enumerateCount && mode != enumerateList {
+		return fmt.Errorf("unknown optimal solutions mode: [%s]", mode)
+	}
+	if weight != 1 {
+		return fmt.Errorf("optimal solutions cannot be enumerated with a weight")
+	}
+	if algorithm == searchConstructive {
+		return fmt.Errorf("optimal solutions cannot be enumerated with the constructive solver")
+	}
+	return nil
+}
+
+// solveAll finds the optimal cost with the search in opts and then runs the
+// last IDA* iteration again, this time going on past every goal it meets. The
+// search never undoes its last move, which no optimal sequence does either,
+// so every sequence it reaches is counted once. A cap of zero means no cap.
+func (p *PuzzleSolution) solveAll(ctx context.Context, opts solveOptions, limit int, keep bool) (*optimalSolutions, error) {
+	first, err := p.solveContext(ctx, opts)
+	if err != nil || first == nil {
+		return nil, err
+	}
+
+	if opts.limits.timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.limits.timeLimit)
+		defer cancel()
+	}
+	budget := newSearchBudget(ctx, opts.limits)
+	budget.prove(first.Cost, first.Cost)
+
+	all := &optimalSolutions{Cost: first.Cost, Length: len(first.Operations)}
+	d := depthFirstSearch{
+		root:   p.searchRoot(),
+		cutOff: first.Cost,
+		budget: budget,
+		ctx:    ctx,
+		onGoal: func(sol *solution) bool {
+			if limit > 0 && all.Count == limit {
+				all.Capped = true
+				return false
+			}
+			all.Count++
+			if keep {
+				all.Solutions = append(all.Solutions, sol)
+			}
+			return true
+		},
+	}
+	d.run()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return all, nil
+}
+
+func (all *optimalSolutions) Print(list bool) {
+	fmt.Println(all.Length)
+	if all.Cost != all.Length {
+		fmt.Printf("%s %d\n", costPrefix, all.Cost)
+	}
+	if all.Capped {
+		fmt.Printf("optimal solutions: at least %d\n", all.Count)
+	} else {
+		fmt.Printf("optimal solutions: %d\n", all.Count)
+	}
+
+	if !list {
+		return
+	}
+	names := make([]string, all.Length)
+	for _, sol := range all.Solutions {
+		names = names[:0]
+		for _, op := range sol.Operations {
+			names = append(names, string(op))
+		}
+		fmt.Println(strings.Join(names, " "))
+	}
+}
+
+// solveAllAndPrint is the counterpart of solveAndPrint for enumerating the
+// optimal solutions.
+func (p *PuzzleSolution) solveAllAndPrint(opts solveOptions, mode string, limit int) error {
+	startTime := time.Now()
+
+	all, err := p.solveAll(context.Background(), opts, limit, mode == enumerateList)
+	if err != nil {
+		return err
+	}
+	if all == nil {
+		fmt.Println("no solution found...")
+		return nil
+	}
+
+	dur := time.Since(startTime)
+	fmt.Printf("%.3f\n", dur.Seconds())
+	all.Print(mode == enumerateList)
+	return nil
+}
@@ -0,0 +1,233 @@
+package puzzle
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+const (
+	generateWalk    = "walk"
+	generateUniform = "uniform"
+
+	maxGenerateAttempts = 1000
+)
+
+var generateModes = []string{generateWalk, generateUniform}
+
+func validateGenerate(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, name := range generateModes {
+		if name == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown generator mode: [%s]", mode)
+}
+
+type generateOptions struct {
+	mode       string
+	count      int
+	walkLength int
+	seed       int64
+	minLength  int
+	maxLength  int
+}
+
+// newEmptyPuzzle sets p up as a rows x cols puzzle with the tiles in order
+// and the blank in the last cell, which is also its goal.
+func (p *PuzzleSolution) newEmptyPuzzle(rows, cols int) error {
+	if rows <= 0 || cols <= 0 {
+		return fmt.Errorf("invalid board size: [%dx%d]", rows, cols)
+	}
+	board, err := newPackedBoard(rows * cols)
+	if err != nil {
+		return err
+	}
+
+	p.puzzleSpec = &puzzleSpec{n: rows*cols - 1, rows: rows, cols: cols}
+	p.goal = orderedGoal(rows, cols, p.n)
+	p.zeroIndex = p.goal[0]
+	p.board = board
+	for tile, c := range p.goal {
+		p.board.set(c.toIndex(cols), tile)
+	}
+	p.currentZero = p.zeroIndex
+	return nil
+}
+
+// randomWalk makes length random moves from p, never undoing the last one
+// unless the board is a single line: there the blank could only go from one
+// end to the other and back.
+func (p *PuzzleSolution) randomWalk(rnd *rand.Rand, length int) (*PuzzleSolution, error) {
+	line := p.rows == 1 || p.cols == 1
+	node := p.searchRoot()
+	for i := 0; i < length; i++ {
+		neighbors := node.Neighbors()
+		if line {
+			neighbors = node.searchRoot().Neighbors()
+		}
+		if len(neighbors) == 0 {
+			return nil, fmt.Errorf("the blank cannot move on a [%dx%d] board", p.rows, p.cols)
+		}
+		node = neighbors[rnd.Intn(len(neighbors))]
+	}
+	return node.searchRoot(), nil
+}
+
+// randomPermutation shuffles the tiles of p uniformly until the board is
+// solvable, which happens for every other permutation. On a single line the
+// tiles cannot pass each other, so only the blank is placed at random among
+// the tiles in their goal order.
+func (p *PuzzleSolution) randomPermutation(rnd *rand.Rand) (*PuzzleSolution, error) {
+	node := p.searchRoot()
+	if p.rows == 1 || p.cols == 1 {
+		cells := make([]int, p.n+1)
+		for tile := 1; tile <= p.n; tile++ {
+			cells[p.goal[tile].toIndex(p.cols)] = tile
+		}
+		blank := p.zeroIndex.toIndex(p.cols)
+		tiles := append(cells[:blank:blank], cells[blank+1:]...)
+
+		blank = rnd.Intn(p.n + 1)
+		tiles = append(tiles[:blank], append([]int{0}, tiles[blank:]...)...)
+		for idx, tile := range tiles {
+			node.board.set(idx, tile)
+		}
+		node.currentZero = coordinateFromIndex(p.cols, blank)
+		return node, nil
+	}
+
+	for {
+		for idx, tile := range rnd.Perm(p.rows * p.cols) {
+			node.board.set(idx, tile)
+			if tile == 0 {
+				node.currentZero = coordinateFromIndex(p.cols, idx)
+			}
+		}
+		if node.IsSolvable() {
+			return node, nil
+		}
+	}
+}
+
+// WritePuzzle prints the puzzle in the format ReadPuzzle expects, directly
+// followed by the goal board when the tiles are not expected in order.
+func (p *PuzzleSolution) WritePuzzle(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	if p.rows == p.cols {
+		fmt.Fprintln(out, p.n)
+	} else {
+		fmt.Fprintln(out, p.rows, p.cols)
+	}
+	blank := p.zeroIndex.toIndex(p.cols)
+	if blank == p.n {
+		fmt.Fprintln(out, -1)
+	} else {
+		fmt.Fprintln(out, blank+1)
+	}
+
+	cells := make([]int, p.rows*p.cols)
+	for idx := range cells {
+		cells[idx] = p.board.get(idx)
+	}
+	writeBoard(out, cells, p.cols)
+
+	ordered := orderedGoal(p.rows, p.cols, blank)
+	for i := range ordered {
+		if ordered[i] != p.goal[i] {
+			for tile, c := range p.goal {
+				cells[c.toIndex(p.cols)] = tile
+			}
+			writeBoard(out, cells, p.cols)
+			break
+		}
+	}
+	return out.Flush()
+}
+
+func writeBoard(w io.Writer, cells []int, cols int) {
+	row := make([]string, cols)
+	for start := 0; start < len(cells); start += cols {
+		for j := range row {
+			row[j] = fmt.Sprint(cells[start+j])
+		}
+		fmt.Fprintln(w, strings.Join(row, " "))
+	}
+}
+
+// optimalLength solves p to tell whether it falls in the requested range.
+func (p *PuzzleSolution) optimalLength(solveOpts solveOptions) (int, error) {
+	sol, err := p.solveContext(context.Background(), solveOpts)
+	if err != nil {
+		return 0, err
+	}
+	if sol == nil {
+		return 0, fmt.Errorf("no solution found")
+	}
+	return len(sol.Operations), nil
+}
+
+// generate prints opts.count solvable instances of the size and goal of p,
+// separated by blank lines. The same seed always gives the same instances.
+// With a length range, boards whose optimal solution, as found with
+// solveOpts, is outside of it are dropped.
+func (p *PuzzleSolution) generate(w io.Writer, opts generateOptions, solveOpts solveOptions) error {
+	rnd := rand.New(rand.NewSource(opts.seed))
+	filtered := opts.minLength > 0 || opts.maxLength > 0
+
+	for i := 0; i < opts.count; i++ {
+		var instance *PuzzleSolution
+		for attempt := 0; instance == nil; attempt++ {
+			if attempt == maxGenerateAttempts {
+				return fmt.Errorf("no instance with an optimal length in [%d, %d] after [%d] attempts",
+					opts.minLength, opts.maxLength, maxGenerateAttempts)
+			}
+
+			var candidate *PuzzleSolution
+			if opts.mode == generateUniform {
+				var err error
+				if candidate, err = p.randomPermutation(rnd); err != nil {
+					return err
+				}
+			} else {
+				var err error
+				if candidate, err = p.randomWalk(rnd, opts.walkLength); err != nil {
+					return err
+				}
+			}
+			if !filtered {
+				instance = candidate
+				break
+			}
+
+			// Boards the solver gives up on count as too hard.
+			length, err := candidate.optimalLength(solveOpts)
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if length >= opts.minLength && (opts.maxLength <= 0 || length <= opts.maxLength) {
+				instance = candidate
+			}
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := instance.WritePuzzle(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
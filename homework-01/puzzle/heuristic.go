@@ -0,0 +1,249 @@
+package puzzle
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// heuristic estimates the number of moves left until the puzzle is solved.
+// Update is called once the tile has been moved from `from` to `to` and must
+// return the same state Compute would, given the state before the move. The
+// search keeps the state of every node it may come back to, so neither of
+// them may change p.
+type heuristic interface {
+	Name() string
+	Compute(p *PuzzleSolution) heuristicState
+	Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState
+}
+
+// heuristicState is what a heuristic knows about a board: the estimate in
+// value, and in aux whatever else it needs to follow a move without looking
+// at the whole board again.
+type heuristicState struct {
+	value int
+	aux   [3]int32
+}
+
+var heuristics = []heuristic{
+	manhattanHeuristic{},
+	linearConflictHeuristic{},
+	misplacedTilesHeuristic{},
+	weightedManhattanHeuristic{},
+}
+
+func heuristicNames() []string {
+	names := make([]string, len(heuristics))
+	for i, h := range heuristics {
+		names[i] = h.Name()
+	}
+	return append(names, patternDatabaseName, walkingDistanceName, walkingDistanceLCName)
+}
+
+func heuristicByName(name string) (heuristic, error) {
+	for _, h := range heuristics {
+		if h.Name() == name {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown heuristic: [%s]", name)
+}
+
+type heuristicOptions struct {
+	name         string
+	pdbPartition string
+	pdbDir       string
+	log          io.Writer
+}
+
+func (p *PuzzleSolution) loadHeuristic(opts heuristicOptions) (heuristic, error) {
+	if p.torus && !torusHeuristic(opts.name) {
+		return nil, fmt.Errorf("heuristic [%s] is not admissible on a torus", opts.name)
+	}
+	if opts.name == patternDatabaseName {
+		return p.loadPatternDatabases(opts.pdbPartition, opts.pdbDir, opts.log)
+	}
+	if opts.name == walkingDistanceName || opts.name == walkingDistanceLCName {
+		return p.loadWalkingDistance(opts.name == walkingDistanceLCName)
+	}
+	return heuristicByName(opts.name)
+}
+
+type manhattanHeuristic struct{}
+
+func (manhattanHeuristic) Name() string {
+	return "manhattan"
+}
+
+func (manhattanHeuristic) Compute(p *PuzzleSolution) heuristicState {
+	dist := 0
+
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			current := coordinate{x: i, y: j}
+			if tile := p.tileAt(i, j); tile != 0 {
+				shouldBe := p.goal[tile]
+				dist += p.distance(shouldBe, current)
+			}
+		}
+	}
+
+	return heuristicState{value: dist}
+}
+
+func (manhattanHeuristic) Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState {
+	destPlace := p.goal[tile]
+	return heuristicState{value: prev.value - p.distance(from, destPlace) + p.distance(to, destPlace)}
+}
+
+// weightedManhattanHeuristic charges every tile its manhattan distance times
+// the cost of moving it, which stays admissible as each of those moves has to
+// be paid for.
+type weightedManhattanHeuristic struct{}
+
+func (weightedManhattanHeuristic) Name() string {
+	return "weighted-manhattan"
+}
+
+func (weightedManhattanHeuristic) Compute(p *PuzzleSolution) heuristicState {
+	dist := 0
+
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			current := coordinate{x: i, y: j}
+			if tile := p.tileAt(i, j); tile != 0 {
+				dist += p.tileCost(tile) * p.distance(p.goal[tile], current)
+			}
+		}
+	}
+
+	return heuristicState{value: dist}
+}
+
+func (weightedManhattanHeuristic) Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState {
+	destPlace := p.goal[tile]
+	return heuristicState{value: prev.value + p.tileCost(tile)*(p.distance(to, destPlace)-p.distance(from, destPlace))}
+}
+
+type misplacedTilesHeuristic struct{}
+
+func (misplacedTilesHeuristic) Name() string {
+	return "misplaced"
+}
+
+func (misplacedTilesHeuristic) Compute(p *PuzzleSolution) heuristicState {
+	count := 0
+
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			if tile := p.tileAt(i, j); tile != 0 && p.goal[tile] != (coordinate{x: i, y: j}) {
+				count++
+			}
+		}
+	}
+
+	return heuristicState{value: count}
+}
+
+func (misplacedTilesHeuristic) Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState {
+	destPlace := p.goal[tile]
+	count := prev.value
+	if from == destPlace {
+		count++
+	}
+	if to == destPlace {
+		count--
+	}
+	return heuristicState{value: count}
+}
+
+// linearConflictHeuristic adds two moves to the manhattan distance for every
+// tile that has to leave its goal row (or column) so that the others in the
+// same line can pass each other.
+type linearConflictHeuristic struct{}
+
+func (linearConflictHeuristic) Name() string {
+	return "linear-conflict"
+}
+
+func (linearConflictHeuristic) Compute(p *PuzzleSolution) heuristicState {
+	dist := manhattanHeuristic{}.Compute(p).value
+	none := coordinate{x: -1, y: -1}
+
+	for row := 0; row < p.rows; row++ {
+		dist += lineConflicts(p, false, row, none, 0)
+	}
+	for col := 0; col < p.cols; col++ {
+		dist += lineConflicts(p, true, col, none, 0)
+	}
+
+	return heuristicState{value: dist}
+}
+
+func (linearConflictHeuristic) Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState {
+	dist := manhattanHeuristic{}.Update(p, prev, tile, from, to).value
+
+	// The order of the tiles inside the line the tile moved along does not
+	// change, so only the two crossing lines have to be recounted.
+	if from.x == to.x {
+		dist += lineConflicts(p, true, from.y, from, 0) - lineConflicts(p, true, from.y, from, tile)
+		dist += lineConflicts(p, true, to.y, to, tile) - lineConflicts(p, true, to.y, to, 0)
+	} else {
+		dist += lineConflicts(p, false, from.x, from, 0) - lineConflicts(p, false, from.x, from, tile)
+		dist += lineConflicts(p, false, to.x, to, tile) - lineConflicts(p, false, to.x, to, 0)
+	}
+
+	return heuristicState{value: dist}
+}
+
+// lineConflicts counts the extra moves needed because of conflicting tiles in
+// a single row (or column when vertical is set). The cell `at` is treated as
+// holding `atTile`, which lets Update look at the line before the move.
+func lineConflicts(p *PuzzleSolution, vertical bool, line int, at coordinate, atTile int) int {
+	var buf [16]int
+	goals := buf[:0]
+
+	length := p.cols
+	if vertical {
+		length = p.rows
+	}
+	for k := 0; k < length; k++ {
+		current := coordinate{x: line, y: k}
+		if vertical {
+			current = coordinate{x: k, y: line}
+		}
+
+		tile := p.tileAt(current.x, current.y)
+		if current == at {
+			tile = atTile
+		}
+		if tile == 0 {
+			continue
+		}
+
+		shouldBe := p.goal[tile]
+		if vertical && shouldBe.y == line {
+			goals = append(goals, shouldBe.x)
+		} else if !vertical && shouldBe.x == line {
+			goals = append(goals, shouldBe.y)
+		}
+	}
+
+	return 2 * (len(goals) - longestIncreasing(goals))
+}
+
+func longestIncreasing(seq []int) int {
+	var buf [16]int
+	tails := buf[:0]
+
+	for _, v := range seq {
+		idx := sort.SearchInts(tails, v)
+		if idx == len(tails) {
+			tails = append(tails, v)
+		} else {
+			tails[idx] = v
+		}
+	}
+
+	return len(tails)
+}
@@ -0,0 +1,47 @@
+package puzzle
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// TestHeuristicsUpdateAndAdmissibility walks randomly from boards of small
+// puzzles and checks, at every step, that the value Update gives after a
+// move is the one Compute gives for the board, and that it never exceeds the
+// distance in the complete state table.
+func TestHeuristicsUpdateAndAdmissibility(t *testing.T) {
+	for _, size := range [][2]int{{3, 3}, {2, 4}} {
+		for _, torus := range []bool{false, true} {
+			p, table := tablePuzzle(t, size[0], size[1], size[0]*size[1]-1, torus)
+
+			for _, name := range heuristicNames() {
+				if torus && !torusHeuristic(name) {
+					continue
+				}
+				t.Run(fmt.Sprintf("%dx%d/torus=%v/%s", size[0], size[1], torus, name), func(t *testing.T) {
+					h, err := p.loadHeuristic(heuristicOptions{name: name, pdbDir: t.TempDir()})
+					if err != nil {
+						t.Fatal(err)
+					}
+					p.heuristic = h
+
+					rnd := rand.New(rand.NewSource(1))
+					for _, node := range solvableBoards(p, table, 20, 2) {
+						for step := 0; step < 50; step++ {
+							fresh := node.searchRoot()
+							if got, want := node.estimateState(), h.Compute(fresh); got != want {
+								t.Fatalf("Update gives %+v after %v, Compute gives %+v", got, node.operations(), want)
+							}
+							if dist := table.distance(node); node.estimate() > dist {
+								t.Fatalf("estimate %d exceeds the distance %d", node.estimate(), dist)
+							}
+							neighbors := node.Neighbors()
+							node = neighbors[rnd.Intn(len(neighbors))]
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package puzzle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// jsonOptions are the options a JSON document may set. Fields it leaves out
+// keep the values given on the command line.
+type jsonOptions struct {
+	Algorithm string  `json:"algorithm"`
+	Heuristic string  `json:"heuristic"`
+	Weight    float64 `json:"weight"`
+	Parallel  bool    `json:"parallel"`
+	Torus     bool    `json:"torus"`
+	TileCosts string  `json:"tileCosts"`
+	Shorten   bool    `json:"shorten"`
+	PDB       string  `json:"pdb"`
+	PDBDir    string  `json:"pdbDir"`
+	TableDir  string  `json:"tableDir"`
+	TimeLimit string  `json:"timeLimit"`
+	MaxNodes  int64   `json:"maxNodes"`
+	MaxMemory uint64  `json:"maxMemoryMiB"`
+	MaxStored int     `json:"maxStored"`
+}
+
+// jsonInput is a puzzle as a JSON document. Blank and Goal mean the same as
+// the blank position and the goal board of the text format.
+type jsonInput struct {
+	Board   [][]int     `json:"board"`
+	Blank   int         `json:"blank"`
+	Goal    [][]int     `json:"goal"`
+	Options jsonOptions `json:"options"`
+}
+
+type jsonResult struct {
+	Moves     []string `json:"moves"`
+	Length    int      `json:"length"`
+	Cost      int      `json:"cost"`
+	Bound     float64  `json:"bound,omitempty"`
+	Seconds   float64  `json:"seconds"`
+	Expanded  int64    `json:"expanded"`
+	Generated int64    `json:"generated"`
+	Algorithm string   `json:"algorithm"`
+	Heuristic string   `json:"heuristic"`
+}
+
+type jsonError struct {
+	Error string `json:"error"`
+	Line  int    `json:"line,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+func validateFormat(format string) error {
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("unknown format: [%s]", format)
+	}
+	return nil
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// fieldLines maps the path of every key in the document, such as
+// options.weight, to the line it is on.
+func fieldLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		for dec.More() {
+			name := prefix
+			if delim == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				name = prefix + fmt.Sprint(key)
+				lines[name] = lineAt(data, dec.InputOffset())
+				name += "."
+			}
+			if err := walk(name); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+
+	walk("")
+	return lines
+}
+
+// decodeError turns the errors of the JSON decoder into an InputError on the
+// line they were found at.
+func decodeError(data []byte, err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &InputError{Line: lineAt(data, syntaxErr.Offset), Field: "document", Err: err}
+	case errors.As(err, &typeErr):
+		return &InputError{Line: lineAt(data, typeErr.Offset), Field: typeErr.Field, Err: err}
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return &InputError{Line: lineAt(data, int64(len(data))), Field: "document", Err: io.ErrUnexpectedEOF}
+	}
+
+	// Unknown fields are only reported by name, so they are looked up.
+	const unknown = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknown) {
+		name := strings.Trim(strings.TrimPrefix(msg, unknown), `"`)
+		line := 0
+		if at := bytes.Index(data, []byte(`"`+name+`"`)); at >= 0 {
+			line = lineAt(data, int64(at))
+		}
+		return &InputError{Line: line, Field: name, Err: errors.New("unknown field")}
+	}
+	return &InputError{Field: "document", Err: err}
+}
+
+// toOptions checks the options of the document, pointing errors at their
+// line.
+func (o jsonOptions) toOptions(lines map[string]int) (Options, error) {
+	fail := func(field string, err error) (Options, error) {
+		return Options{}, &InputError{Line: lines["options."+field], Field: "options." + field, Err: err}
+	}
+
+	found := false
+	for _, name := range searchAlgorithms {
+		found = found || name == o.Algorithm
+	}
+	if !found {
+		return fail("algorithm", fmt.Errorf("unknown search algorithm: [%s]", o.Algorithm))
+	}
+	found = false
+	for _, name := range heuristicNames() {
+		found = found || name == o.Heuristic
+	}
+	if !found {
+		return fail("heuristic", fmt.Errorf("unknown heuristic: [%s]", o.Heuristic))
+	}
+	if o.Weight < 1 {
+		return fail("weight", fmt.Errorf("weight must be at least 1, found: [%v]", o.Weight))
+	}
+	if err := validateMaxStored(o.MaxStored); err != nil {
+		return fail("maxStored", err)
+	}
+	var timeLimit time.Duration
+	if o.TimeLimit != "" {
+		var err error
+		if timeLimit, err = time.ParseDuration(o.TimeLimit); err != nil {
+			return fail("timeLimit", err)
+		}
+	}
+
+	return Options{
+		Algorithm:          o.Algorithm,
+		Heuristic:          o.Heuristic,
+		Weight:             o.Weight,
+		Parallel:           o.Parallel,
+		Torus:              o.Torus,
+		TileCosts:          o.TileCosts,
+		Shorten:            o.Shorten,
+		PatternDatabase:    o.PDB,
+		PatternDatabaseDir: o.PDBDir,
+		TableDir:           o.TableDir,
+		TimeLimit:          timeLimit,
+		MaxNodes:           o.MaxNodes,
+		MaxMemory:          o.MaxMemory << 20,
+		MaxStored:          o.MaxStored,
+	}, nil
+}
+
+func solveJSON(data []byte, defaults jsonOptions, log io.Writer) (*jsonResult, error) {
+	doc := jsonInput{Options: defaults}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, decodeError(data, err)
+	}
+	lines := fieldLines(data)
+
+	p, err := New(doc.Board, Goal{Blank: doc.Blank, Board: doc.Goal})
+	var inputErr *InputError
+	if errors.As(err, &inputErr) {
+		inputErr.Line = lines[inputErr.Field]
+	}
+	if err != nil {
+		return nil, err
+	}
+	opts, err := doc.Options.toOptions(lines)
+	if err != nil {
+		return nil, err
+	}
+	opts.Log = log
+
+	res, err := p.Solve(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	total := res.Stats.total()
+	return &jsonResult{
+		Moves:     res.Moves,
+		Length:    len(res.Moves),
+		Cost:      res.Cost,
+		Bound:     res.Bound,
+		Seconds:   res.Duration.Seconds(),
+		Expanded:  total.Expanded,
+		Generated: total.Generated,
+		Algorithm: res.Stats.Algorithm,
+		Heuristic: res.Stats.Heuristic,
+	}, nil
+}
+
+// runJSON reads a puzzle as a JSON document from r, solves it and writes
+// the result to w as JSON as well, while the progress goes to log. Errors are
+// written to w too, with the line and field of the document at fault when
+// there is one, and returned.
+func runJSON(r io.Reader, w, log io.Writer, defaults jsonOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	res, err := solveJSON(data, defaults, log)
+	if err != nil {
+		out := jsonError{Error: err.Error()}
+		var inputErr *InputError
+		if errors.As(err, &inputErr) {
+			out.Line, out.Field = inputErr.Line, inputErr.Field
+		}
+		enc.Encode(out)
+		return err
+	}
+	return enc.Encode(res)
+}
@@ -0,0 +1,175 @@
+package puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errNodeLimit   = errors.New("node limit exceeded")
+	errMemoryLimit = errors.New("memory limit exceeded")
+	errStoredLimit = errors.New("no solution fits in the stored nodes")
+)
+
+// searchLimits bounds a single solve. Zero values mean no limit.
+type searchLimits struct {
+	timeLimit time.Duration
+	maxNodes  int64
+	maxMemory uint64
+}
+
+// LimitError is returned when a search is stopped before finding a solution.
+// It carries what was proven until then: no solution costs less than
+// LowerBound moves.
+type LimitError struct {
+	Err        error
+	LowerBound int
+	CutOff     int
+	Expanded   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v after [%d] expanded nodes: optimal cost is at least [%d], last cut-off [%d]",
+		e.Err, e.Expanded, e.LowerBound, e.CutOff)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// searchBudget counts the nodes of a solve, which may be expanded on several
+// goroutines, stops it once one of its limits is hit and keeps the statistics
+// of every cut-off it went through.
+type searchBudget struct {
+	ctx        context.Context
+	limits     searchLimits
+	expanded   int64
+	generated  int64
+	peak       int64
+	deepest    int64
+	lowerBound int
+	cutOff     int
+
+	iterations []IterationStats
+	started    time.Time
+	current    IterationStats
+}
+
+func newSearchBudget(ctx context.Context, limits searchLimits) *searchBudget {
+	return &searchBudget{ctx: ctx, limits: limits, cutOff: -1}
+}
+
+// prove records a new lower bound on the optimal cost together with the
+// cut-off the search is about to try, which starts a new iteration when it
+// is higher than the last one. It is only called by the goroutine driving
+// the search, while no nodes are being expanded.
+func (b *searchBudget) prove(lowerBound, cutOff int) {
+	if lowerBound > b.lowerBound {
+		b.lowerBound = lowerBound
+	}
+	if cutOff > b.cutOff {
+		b.finishIteration()
+		b.cutOff = cutOff
+		b.started = time.Now()
+		b.current = IterationStats{CutOff: cutOff, Expanded: b.expanded, Generated: b.generated}
+		b.peak = 0
+		b.deepest = 0
+	}
+}
+
+func (b *searchBudget) finishIteration() {
+	if b.started.IsZero() {
+		return
+	}
+	it := b.current
+	it.LowerBound = b.lowerBound
+	it.Expanded = atomic.LoadInt64(&b.expanded) - it.Expanded
+	it.Generated = atomic.LoadInt64(&b.generated) - it.Generated
+	it.PeakFrontier = atomic.LoadInt64(&b.peak)
+	it.Depth = int(atomic.LoadInt64(&b.deepest))
+	it.BranchingFactor = effectiveBranchingFactor(it.Generated, it.Depth)
+	it.Seconds = time.Since(b.started).Seconds()
+	b.iterations = append(b.iterations, it)
+	b.started = time.Time{}
+}
+
+// generate accounts for n new nodes, the deepest of them depth moves away
+// from the root.
+func (b *searchBudget) generate(n, depth int) {
+	atomic.AddInt64(&b.generated, int64(n))
+	if n > 0 {
+		raise(&b.deepest, depth)
+	}
+}
+
+// frontier records the number of nodes the search keeps around: the open
+// list of a best-first search or the current path of a depth-first one.
+func (b *searchBudget) frontier(size int) {
+	raise(&b.peak, size)
+}
+
+// raise sets *addr to value unless it already holds more.
+func raise(addr *int64, value int) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if int64(value) <= old || atomic.CompareAndSwapInt64(addr, old, int64(value)) {
+			return
+		}
+	}
+}
+
+// expand accounts for one more expanded node. The context, which may be one
+// derived from the budget's, and the memory in use are only looked at every
+// cancelCheckInterval nodes.
+func (b *searchBudget) expand(ctx context.Context) error {
+	expanded := atomic.AddInt64(&b.expanded, 1)
+	if b.limits.maxNodes > 0 && expanded > b.limits.maxNodes {
+		return b.exceeded(errNodeLimit)
+	}
+	if expanded%cancelCheckInterval != 0 {
+		return nil
+	}
+	return b.check(ctx)
+}
+
+// add accounts at once for the nodes a search counted on its own, so that
+// searches running on several goroutines only touch the shared counters every
+// so often, and then checks every limit.
+func (b *searchBudget) add(ctx context.Context, expanded, generated int64, peak, deepest int) error {
+	total := atomic.AddInt64(&b.expanded, expanded)
+	atomic.AddInt64(&b.generated, generated)
+	b.frontier(peak)
+	raise(&b.deepest, deepest)
+	if b.limits.maxNodes > 0 && total > b.limits.maxNodes {
+		return b.exceeded(errNodeLimit)
+	}
+	return b.check(ctx)
+}
+
+// check stops the search once ctx is done or too much memory is in use.
+func (b *searchBudget) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return b.exceeded(err)
+	}
+	if b.limits.maxMemory > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc > b.limits.maxMemory {
+			return b.exceeded(errMemoryLimit)
+		}
+	}
+	return nil
+}
+
+func (b *searchBudget) exceeded(err error) error {
+	return &LimitError{
+		Err:        err,
+		LowerBound: b.lowerBound,
+		CutOff:     b.cutOff,
+		Expanded:   atomic.LoadInt64(&b.expanded),
+	}
+}
@@ -0,0 +1,116 @@
+package puzzle
+
+import (
+	"context"
+	"sync"
+)
+
+const workItemsPerWorker = 16
+
+// splitFrontier expands the tree breadth-first (with the same pruning as the
+// depth-first search) until there are enough work items for the workers. A
+// goal met on the way is returned directly when every move costs the same,
+// being the shallowest one. Otherwise it is kept as a work item, as a deeper
+// goal may be cheaper.
+func (p *PuzzleSolution) splitFrontier(budget *searchBudget, workers int) ([]*PuzzleSolution, *solution, error) {
+	frontier := []*PuzzleSolution{p}
+	for len(frontier) < workers*workItemsPerWorker {
+		var next []*PuzzleSolution
+		for _, node := range frontier {
+			if node.IsGoal() {
+				if p.costs == nil {
+					return nil, node.toSolution(), nil
+				}
+				next = append(next, node)
+				continue
+			}
+			if err := budget.expand(budget.ctx); err != nil {
+				return nil, nil, err
+			}
+			children := node.Neighbors()
+			budget.generate(len(children), node.depth+1)
+			next = append(next, children...)
+		}
+		if len(next) <= len(frontier) {
+			break
+		}
+		frontier = next
+		budget.frontier(len(frontier))
+	}
+	return frontier, nil, nil
+}
+
+// solveParallel runs every IDA* iteration over the work items of a shallow
+// split of the tree. Each iteration only starts once the previous one proved
+// there is nothing within its cut-off, so the first solution found is optimal
+// and the remaining workers are cancelled.
+func (p *PuzzleSolution) solveParallel(budget *searchBudget, workers int, weight weighting) (*solution, error) {
+	cutOff := weight.f(p.cost, p.estimate())
+	budget.prove(weight.lowerBound(cutOff), weight.moves(cutOff))
+	frontier, found, err := p.splitFrontier(budget, workers)
+	if found != nil || err != nil {
+		return found, err
+	}
+
+	for cutOff != unreachable {
+		var (
+			mu         sync.Mutex
+			wg         sync.WaitGroup
+			pot        *solution
+			failure    error
+			nextCutOff = unreachable
+		)
+
+		budget.prove(weight.lowerBound(cutOff), weight.moves(cutOff))
+		ctx, cancel := context.WithCancel(budget.ctx)
+		items := make(chan *PuzzleSolution)
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range items {
+					d := depthFirstSearch{root: item, cutOff: cutOff, weight: weight, budget: budget, ctx: ctx}
+					sol, f := d.run()
+
+					mu.Lock()
+					if sol != nil && pot == nil {
+						pot = sol
+						cancel()
+					}
+					if d.err != nil && failure == nil {
+						failure = d.err
+						cancel()
+					}
+					if f < nextCutOff {
+						nextCutOff = f
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+
+	feed:
+		for _, item := range frontier {
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(items)
+		wg.Wait()
+		cancel()
+
+		if pot != nil {
+			pot.Bound = provenBound(pot.Cost, weight.lowerBound(cutOff), weight)
+			return pot, nil
+		}
+		if failure != nil {
+			return nil, failure
+		}
+		cutOff = nextCutOff
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,111 @@
+package puzzle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// pdbState is a placement of the pattern tiles together with the blank, as
+// the reference search below keeps it.
+type pdbState struct {
+	positions [pdbMaxPattern]int
+	blank     int
+}
+
+// referenceDistances runs a 0-1 breadth-first search over a map of states,
+// apart from build, and returns the least number of pattern moves for every
+// placement of the tiles of db that it reaches.
+func referenceDistances(db *patternDatabase) map[[pdbMaxPattern]int]int {
+	start := pdbState{blank: db.blank}
+	copy(start.positions[:], db.goals)
+
+	seen := map[pdbState]int{start: 0}
+	level := []pdbState{start}
+	for depth := 0; len(level) > 0; depth++ {
+		var next []pdbState
+		for i := 0; i < len(level); i++ {
+			state := level[i]
+			if seen[state] != depth {
+				continue
+			}
+
+			c := coordinateFromIndex(db.cols, state.blank)
+			for _, d := range []coordinate{{x: -1}, {x: 1}, {y: -1}, {y: 1}} {
+				nb := coordinate{x: c.x + d.x, y: c.y + d.y}
+				if nb.x < 0 || nb.x >= db.rows || nb.y < 0 || nb.y >= db.cols {
+					continue
+				}
+
+				moved, cost := state, 0
+				moved.blank = nb.toIndex(db.cols)
+				for s := range db.tiles {
+					if state.positions[s] == moved.blank {
+						moved.positions[s] = state.blank
+						cost = 1
+					}
+				}
+				if dist, ok := seen[moved]; ok && dist <= depth+cost {
+					continue
+				}
+				seen[moved] = depth + cost
+				if cost == 0 {
+					level = append(level, moved)
+				} else {
+					next = append(next, moved)
+				}
+			}
+		}
+		level = next
+	}
+
+	placements := make(map[[pdbMaxPattern]int]int)
+	for state, dist := range seen {
+		if best, ok := placements[state.positions]; !ok || dist < best {
+			placements[state.positions] = dist
+		}
+	}
+	return placements
+}
+
+func TestPatternDatabaseMatchesReference(t *testing.T) {
+	cases := []struct {
+		rows, cols, blank int
+		tiles             []int
+	}{
+		{rows: 3, cols: 4, blank: 11, tiles: []int{1, 2, 3}},
+		{rows: 3, cols: 4, blank: 11, tiles: []int{4, 8, 11}},
+		{rows: 3, cols: 4, blank: 0, tiles: []int{1, 6, 11}},
+		{rows: 2, cols: 4, blank: 7, tiles: []int{1, 2, 5, 6}},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%dx%d/blank=%d/%v", c.rows, c.cols, c.blank, c.tiles), func(t *testing.T) {
+			p := &PuzzleSolution{}
+			if err := p.newEmptyPuzzle(c.rows, c.cols); err != nil {
+				t.Fatal(err)
+			}
+			p.goal = orderedGoal(c.rows, c.cols, c.blank)
+			p.zeroIndex = p.goal[0]
+
+			db := newPatternDatabase(p, c.tiles)
+			db.build()
+			want := referenceDistances(db)
+
+			positions := make([]int, len(c.tiles))
+			taken := make([]bool, db.cells)
+			for rank := range db.dist {
+				db.unrank(rank, positions, taken)
+				var placement [pdbMaxPattern]int
+				copy(placement[:], positions)
+
+				dist, ok := want[placement]
+				if !ok {
+					dist = int(pdbUnvisited)
+				}
+				if int(db.dist[rank]) != dist {
+					t.Fatalf("placement %v: got %d, want %d", positions, db.dist[rank], dist)
+				}
+			}
+		})
+	}
+}
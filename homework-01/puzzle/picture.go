@@ -0,0 +1,139 @@
+package puzzle
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/rand"
+	"os"
+)
+
+const (
+	gifFrameDelay = 20
+	gifLastDelay  = 200
+)
+
+// picturePuzzle cuts an image into the tiles of a puzzle. The image is
+// reduced to a fixed palette once, so that frames are built by copying
+// palette indices around.
+type picturePuzzle struct {
+	source *image.Paletted
+	tileW  int
+	tileH  int
+}
+
+func loadPicture(path string, rows, cols int) (*picturePuzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	pic := &picturePuzzle{tileW: bounds.Dx() / cols, tileH: bounds.Dy() / rows}
+	if pic.tileW < 2 || pic.tileH < 2 {
+		return nil, fmt.Errorf("image of [%dx%d] pixels is too small for [%dx%d] tiles", bounds.Dx(), bounds.Dy(), rows, cols)
+	}
+
+	pic.source = image.NewPaletted(image.Rect(0, 0, pic.tileW*cols, pic.tileH*rows), palette.Plan9)
+	draw.FloydSteinberg.Draw(pic.source, pic.source.Bounds(), img, bounds.Min)
+	return pic, nil
+}
+
+func (pic *picturePuzzle) cell(c coordinate) image.Rectangle {
+	return image.Rect(c.y*pic.tileW, c.x*pic.tileH, (c.y+1)*pic.tileW, (c.x+1)*pic.tileH)
+}
+
+// frame draws every tile of p where it currently stands. The blank and a
+// one pixel border around the tiles are left black, the first colour of the
+// palette.
+func (pic *picturePuzzle) frame(p *PuzzleSolution) *image.Paletted {
+	img := image.NewPaletted(pic.source.Bounds(), palette.Plan9)
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			tile := p.tileAt(i, j)
+			if tile == 0 {
+				continue
+			}
+
+			from := pic.cell(p.goal[tile])
+			to := pic.cell(coordinate{x: i, y: j})
+			for y := 0; y < pic.tileH-1; y++ {
+				src := pic.source.PixOffset(from.Min.X, from.Min.Y+y)
+				dst := img.PixOffset(to.Min.X, to.Min.Y+y)
+				copy(img.Pix[dst:dst+pic.tileW-1], pic.source.Pix[src:src+pic.tileW-1])
+			}
+		}
+	}
+	return img
+}
+
+// animate replays ops from p, one frame per board.
+func (pic *picturePuzzle) animate(p *PuzzleSolution, ops []operation) *gif.GIF {
+	board := p.searchRoot()
+	anim := &gif.GIF{}
+	add := func(delay int) {
+		anim.Image = append(anim.Image, pic.frame(board))
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	add(gifFrameDelay)
+	for _, op := range ops {
+		board.apply(op)
+		add(gifFrameDelay)
+	}
+	anim.Delay[len(anim.Delay)-1] = gifLastDelay
+	return anim
+}
+
+// solvePicture cuts the image at imagePath into rows x cols tiles, scrambles
+// them with a random walk from the solved picture, solves the puzzle and
+// writes the solution as an animated GIF to gifPath.
+func solvePicture(imagePath, gifPath string, rows, cols int, gen generateOptions, opts solveOptions, hOpts heuristicOptions) error {
+	pic, err := loadPicture(imagePath, rows, cols)
+	if err != nil {
+		return err
+	}
+
+	goal := PuzzleSolution{}
+	if err := goal.newEmptyPuzzle(rows, cols); err != nil {
+		return err
+	}
+	goal.torus = opts.torus
+	if goal.heuristic, err = goal.loadHeuristic(hOpts); err != nil {
+		return err
+	}
+	p, err := goal.randomWalk(rand.New(rand.NewSource(gen.seed)), gen.walkLength)
+	if err != nil {
+		return err
+	}
+
+	sol, err := p.solveContext(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	if sol == nil {
+		return fmt.Errorf("no solution found")
+	}
+	sol.Print()
+
+	f, err := os.Create(gifPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := gif.EncodeAll(f, pic.animate(p, sol.Operations)); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,168 @@
+package puzzle
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	agentLRTA = "lrta"
+	agentRTA  = "rta"
+)
+
+var agentModes = []string{agentLRTA, agentRTA}
+
+func validateAgent(mode string, lookahead int) error {
+	if mode == "" {
+		return nil
+	}
+	if mode != agentLRTA && mode != agentRTA {
+		return fmt.Errorf("unknown agent: [%s]", mode)
+	}
+	if lookahead < 1 {
+		return fmt.Errorf("lookahead must be at least 1, found: [%d]", lookahead)
+	}
+	return nil
+}
+
+type agentOptions struct {
+	mode      string
+	lookahead int
+	trials    int
+	maxMoves  int
+}
+
+// realTimeAgent commits to one move at a time after looking a fixed number of
+// moves ahead, and learns better estimates of the boards it leaves. LRTA*
+// raises the estimate of a board to the best value seen from it, which keeps
+// estimates admissible, so repeated trials converge to an optimal path. RTA*
+// stores the second best value instead: that is what going back to the board
+// costs within a trial, but it can overestimate in later ones.
+type realTimeAgent struct {
+	rta       bool
+	lookahead int
+	learned   map[boardKey]int
+	updates   int
+}
+
+func (a *realTimeAgent) estimate(p *PuzzleSolution) int {
+	if h, ok := a.learned[p.board.key()]; ok {
+		return h
+	}
+	return p.estimate()
+}
+
+// value is the cheapest cost of reaching, within depth moves from p, either
+// the goal or a board, plus its estimate. It is never below the estimate of
+// p itself: a board learned about while looking deeper would otherwise look
+// cheaper from its neighbours than it is, and the agent could go round in
+// circles without learning anything.
+func (a *realTimeAgent) value(p *PuzzleSolution, depth int) int {
+	if p.isGoalBoard() {
+		return 0
+	}
+	best := a.estimate(p)
+	if depth == 0 {
+		return best
+	}
+
+	ahead := unreachable
+	for _, next := range p.Neighbors() {
+		if v := next.cost - p.cost + a.value(next, depth-1); v < ahead {
+			ahead = v
+		}
+	}
+	if ahead > best {
+		return ahead
+	}
+	return best
+}
+
+// step picks the move out of p with the best lookahead value, updates the
+// estimate of p and returns the board the move leads to.
+func (a *realTimeAgent) step(p *PuzzleSolution) *PuzzleSolution {
+	var chosen *PuzzleSolution
+	best, second := unreachable, unreachable
+	for _, next := range p.Neighbors() {
+		v := next.cost - p.cost + a.value(next, a.lookahead-1)
+		switch {
+		case v < best:
+			chosen, best, second = next, v, best
+		case v < second:
+			second = v
+		}
+	}
+
+	learned := best
+	if a.rta && second != unreachable {
+		learned = second
+	}
+	if old := a.estimate(p); learned != old && (a.rta || learned > old) {
+		a.learned[p.board.key()] = learned
+		a.updates++
+	}
+	return chosen
+}
+
+type agentTrial struct {
+	moves    int
+	cost     int
+	updates  int
+	estimate int
+	solved   bool
+}
+
+// trial runs the agent from p until it reaches the goal or makes maxMoves
+// moves. Every board is turned into a root, so that the agent may go back
+// the way it came.
+func (a *realTimeAgent) trial(p *PuzzleSolution, maxMoves int) agentTrial {
+	a.updates = 0
+	res := agentTrial{estimate: a.estimate(p)}
+
+	node := p.searchRoot()
+	for !node.isGoalBoard() && res.moves < maxMoves {
+		next := a.step(node)
+		res.moves++
+		res.cost += next.cost - node.cost
+		node = next.searchRoot()
+	}
+
+	res.solved = node.isGoalBoard()
+	res.updates = a.updates
+	return res
+}
+
+// runAgent solves p over and over with a real-time agent sharing what it has
+// learned between trials, and prints the moves of every trial. It stops once
+// a trial learns nothing new, as the following ones would follow the same
+// path.
+func (p *PuzzleSolution) runAgent(w io.Writer, opts agentOptions, tileCosts string) error {
+	if err := p.setTileCosts(tileCosts); err != nil {
+		return err
+	}
+	agent := &realTimeAgent{rta: opts.mode == agentRTA, lookahead: opts.lookahead, learned: make(map[boardKey]int)}
+
+	total, trials := 0, 0
+	converged := false
+	for trials < opts.trials && !converged {
+		res := agent.trial(p, opts.maxMoves)
+		trials++
+		total += res.moves
+
+		status := "solved"
+		if !res.solved {
+			status = "gave up"
+		}
+		fmt.Fprintf(w, "trial %d: %s, moves %d, cost %d, start estimate %d, updates %d, learned %d\n",
+			trials, status, res.moves, res.cost, res.estimate, res.updates, len(agent.learned))
+		converged = res.solved && res.updates == 0
+	}
+
+	fmt.Fprintf(w, "total moves: %d over %d trials\n", total, trials)
+	if converged {
+		fmt.Fprintf(w, "converged after %d trials\n", trials)
+	} else {
+		fmt.Fprintf(w, "not converged after %d trials\n", trials)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package puzzle
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+const defaultMaxStored = 1000000
+
+func validateMaxStored(maxStored int) error {
+	if maxStored < 2 {
+		return fmt.Errorf("stored nodes must be at least 2, found: [%d]", maxStored)
+	}
+	return nil
+}
+
+// smaNode is a node of the tree kept by SMA*. Moves are tried one at a time,
+// next being the index in operations of the first one not tried yet. A child
+// dropped to make room leaves its f-value in forgotten, so that its parent
+// knows what regenerating it is worth.
+type smaNode struct {
+	node      *PuzzleSolution
+	parent    *smaNode
+	children  []*smaNode
+	forgotten map[operation]int
+	next      int
+	f         int
+
+	// best is the f-value of the best child the node can still add to the
+	// tree, and the indexes are its places in the heaps of the search.
+	best    int
+	openIdx int
+	leafIdx int
+}
+
+func newSMANode(node *PuzzleSolution, parent *smaNode) *smaNode {
+	n := &smaNode{node: node, parent: parent, openIdx: -1, leafIdx: -1}
+	n.skipInvalid()
+	return n
+}
+
+// skipInvalid moves next past the moves that leave the board or undo the
+// last one.
+func (n *smaNode) skipInvalid() {
+	for ; n.next < len(operations); n.next++ {
+		op := operations[n.next]
+		if n.node.parent != nil && n.node.op == op.opposite() {
+			continue
+		}
+		if _, ok := n.node.step(n.node.currentZero, op); ok {
+			return
+		}
+	}
+}
+
+// nextF is the f-value of the next child of n: its own while some moves were
+// never tried, as they cannot do better, and otherwise the smallest forgotten
+// one.
+func (n *smaNode) nextF() int {
+	if n.next < len(operations) {
+		return n.f
+	}
+	best := unreachable
+	for _, f := range n.forgotten {
+		if f < best {
+			best = f
+		}
+	}
+	return best
+}
+
+// smaHeap is a heap of tree nodes which keeps their indexes up to date, so
+// that a node can be moved or taken out when its values change.
+type smaHeap struct {
+	nodes []*smaNode
+	less  func(a, b *smaNode) bool
+	index func(n *smaNode) *int
+}
+
+func (h *smaHeap) Len() int {
+	return len(h.nodes)
+}
+
+func (h *smaHeap) Less(i, j int) bool {
+	return h.less(h.nodes[i], h.nodes[j])
+}
+
+func (h *smaHeap) Swap(i, j int) {
+	h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i]
+	*h.index(h.nodes[i]) = i
+	*h.index(h.nodes[j]) = j
+}
+
+func (h *smaHeap) Push(x interface{}) {
+	n := x.(*smaNode)
+	*h.index(n) = len(h.nodes)
+	h.nodes = append(h.nodes, n)
+}
+
+func (h *smaHeap) Pop() interface{} {
+	n := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	*h.index(n) = -1
+	return n
+}
+
+// set puts n in the heap or moves it to its place when in is true, and takes
+// it out otherwise.
+func (h *smaHeap) set(n *smaNode, in bool) {
+	idx := *h.index(n)
+	switch {
+	case in && idx < 0:
+		heap.Push(h, n)
+	case in:
+		heap.Fix(h, idx)
+	case idx >= 0:
+		heap.Remove(h, idx)
+	}
+}
+
+// smaSearch is the state of SMA* (Russell, 1992), a best-first search that
+// keeps at most maxStored nodes. The open heap holds the nodes that can still
+// add a child, the best and deepest first; the leaves heap holds the nodes
+// without children, the worst and shallowest first, to be dropped when the
+// tree is full.
+type smaSearch struct {
+	weight    weighting
+	maxStored int
+	stored    int
+	open      smaHeap
+	leaves    smaHeap
+	truncated bool
+}
+
+func newSMASearch(weight weighting, maxStored int) *smaSearch {
+	s := &smaSearch{weight: weight, maxStored: maxStored}
+	s.open = smaHeap{
+		less: func(a, b *smaNode) bool {
+			if a.best != b.best {
+				return a.best < b.best
+			}
+			return a.node.depth > b.node.depth
+		},
+		index: func(n *smaNode) *int { return &n.openIdx },
+	}
+	s.leaves = smaHeap{
+		less: func(a, b *smaNode) bool {
+			if a.f != b.f {
+				return a.f > b.f
+			}
+			return a.node.depth < b.node.depth
+		},
+		index: func(n *smaNode) *int { return &n.leafIdx },
+	}
+	return s
+}
+
+// update puts n back in its place in both heaps after a change.
+func (s *smaSearch) update(n *smaNode) {
+	n.best = n.nextF()
+	s.open.set(n, n.best != unreachable)
+	s.leaves.set(n, len(n.children) == 0 && n.parent != nil)
+}
+
+// generate adds the next child of n to the tree: the first move not tried
+// yet, or else the forgotten child with the smallest f-value, which it gets
+// back. A child that is not the goal and is as deep as a full tree allows can
+// never lead to a solution, so its f-value is unreachable.
+func (s *smaSearch) generate(n *smaNode) *smaNode {
+	var op operation
+	known := 0
+	if n.next < len(operations) {
+		op = operations[n.next]
+		n.next++
+		n.skipInvalid()
+	} else {
+		known = unreachable
+		for _, candidate := range operations {
+			if f, ok := n.forgotten[candidate]; ok && f < known {
+				op, known = candidate, f
+			}
+		}
+		delete(n.forgotten, op)
+	}
+
+	next, _ := n.node.move(op)
+	child := newSMANode(next, n)
+	child.f = s.weight.f(next.cost, next.estimate())
+	if n.f > child.f {
+		child.f = n.f
+	}
+	if known > child.f {
+		child.f = known
+	}
+	if next.depth >= s.maxStored-1 && !next.IsGoal() {
+		child.f = unreachable
+		s.truncated = true
+	}
+
+	n.children = append(n.children, child)
+	s.stored++
+	s.update(child)
+	s.update(n)
+	return child
+}
+
+// backUp sets the f-value of n, once all its moves were tried, to the best
+// one of its children, remembered or forgotten, and carries the change up to
+// its ancestors.
+func (s *smaSearch) backUp(n *smaNode) {
+	for ; n != nil && n.next == len(operations); n = n.parent {
+		best := unreachable
+		for _, child := range n.children {
+			if child.f < best {
+				best = child.f
+			}
+		}
+		for _, f := range n.forgotten {
+			if f < best {
+				best = f
+			}
+		}
+		if best == n.f {
+			return
+		}
+		n.f = best
+		s.update(n)
+	}
+}
+
+// drop takes the worst leaf out of the tree, leaving its f-value to its
+// parent.
+func (s *smaSearch) drop() {
+	leaf := heap.Pop(&s.leaves).(*smaNode)
+	s.open.set(leaf, false)
+
+	parent := leaf.parent
+	for i, child := range parent.children {
+		if child == leaf {
+			last := len(parent.children) - 1
+			parent.children[i] = parent.children[last]
+			parent.children = parent.children[:last]
+			break
+		}
+	}
+	if parent.forgotten == nil {
+		parent.forgotten = make(map[operation]int)
+	}
+	parent.forgotten[leaf.node.op] = leaf.f
+	s.stored--
+	s.update(parent)
+}
+
+// solveSMA runs SMA*, which expands nodes by f-value like A* but keeps at
+// most maxStored of them, dropping the worst leaves when the tree is full.
+// Their parents remember the f-values of what was dropped and regenerate it
+// only once it is the best left. The solution is optimal as long as its path
+// fits in the tree; when no solution does, a *LimitError is returned.
+func (p *PuzzleSolution) solveSMA(budget *searchBudget, weight weighting, maxStored int) (*solution, error) {
+	s := newSMASearch(weight, maxStored)
+	root := newSMANode(p.searchRoot(), nil)
+	root.f = weight.f(root.node.cost, root.node.estimate())
+	s.stored = 1
+	s.update(root)
+
+	for s.open.Len() > 0 {
+		n := s.open.nodes[0]
+		budget.prove(weight.lowerBound(n.best), weight.moves(n.best))
+		if n.node.IsGoal() {
+			sol := n.node.toSolution()
+			sol.Bound = provenBound(sol.Cost, weight.lowerBound(n.best), weight)
+			return sol, nil
+		}
+		if err := budget.expand(budget.ctx); err != nil {
+			return nil, err
+		}
+
+		child := s.generate(n)
+		budget.generate(1, child.node.depth)
+		s.backUp(n)
+		if s.stored > s.maxStored {
+			// The new child is kept out of the way, as dropping it would
+			// only undo the expansion.
+			s.leaves.set(child, false)
+			for s.stored > s.maxStored {
+				s.drop()
+			}
+			s.update(child)
+		}
+		budget.frontier(s.stored)
+	}
+
+	if s.truncated {
+		return nil, budget.exceeded(errStoredLimit)
+	}
+	return nil, nil
+}
@@ -0,0 +1,793 @@
+package puzzle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type operation string
+
+const (
+	operationLeft  = operation("left")
+	operationRight = operation("right")
+	operationUp    = operation("up")
+	operationDown  = operation("down")
+)
+
+const (
+	unreachable         = math.MaxInt32
+	cancelCheckInterval = 1 << 12
+)
+
+var operations = []operation{operationDown, operationLeft, operationRight, operationUp}
+
+func (op operation) opposite() operation {
+	switch op {
+	case operationLeft:
+		return operationRight
+	case operationRight:
+		return operationLeft
+	case operationUp:
+		return operationDown
+	}
+	return operationUp
+}
+
+// blankShift is the direction the blank travels in when the tile moves by op.
+func (op operation) blankShift() coordinate {
+	switch op {
+	case operationLeft:
+		return coordinate{y: 1}
+	case operationRight:
+		return coordinate{y: -1}
+	case operationUp:
+		return coordinate{x: 1}
+	}
+	return coordinate{x: -1}
+}
+
+type coordinate struct {
+	x int
+	y int
+}
+
+type solution struct {
+	Operations []operation
+	Cost       int
+	Bound      float64
+}
+
+func (s *solution) Print() {
+	fmt.Println(len(s.Operations))
+	for _, op := range s.Operations {
+		fmt.Println(op)
+	}
+	if s.Cost != len(s.Operations) {
+		fmt.Printf("%s %d\n", costPrefix, s.Cost)
+	}
+	if s.Bound > 0 {
+		fmt.Printf("suboptimality bound: %.3f\n", s.Bound)
+	}
+}
+
+func (c *coordinate) toIndex(cols int) int {
+	return c.x*cols + c.y
+}
+
+func coordinateFromIndex(cols int, idx int) coordinate {
+	return coordinate{
+		x: idx / cols,
+		y: idx % cols,
+	}
+}
+
+// puzzleSpec is the part of the problem shared by every node of the search.
+type puzzleSpec struct {
+	n         int
+	rows      int
+	cols      int
+	zeroIndex coordinate
+	goal      []coordinate
+	costs     []int
+	torus     bool
+	heuristic heuristic
+}
+
+type PuzzleSolution struct {
+	*puzzleSpec
+	board          packedBoard
+	currentZero    coordinate
+	heuristicState heuristicState
+	heuristicKnown bool
+	parent         *PuzzleSolution
+	op             operation
+	depth          int
+	cost           int
+}
+
+func retrieveNumbers(line string, expectedCount int) ([]int, error) {
+	sep := strings.Fields(line)
+	if len(sep) != expectedCount {
+		return nil, fmt.Errorf("expected number count: [%d], found count: [%d]", expectedCount, len(sep))
+	}
+
+	nums := make([]int, len(sep))
+	for i, s := range sep {
+		num, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = num
+	}
+
+	return nums, nil
+}
+
+// readLine returns the next line, including a last one without a newline.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return line, nil
+	}
+	return line, err
+}
+
+// lineReader counts the lines read so far, so that input errors can point at
+// the line at fault.
+type lineReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (r *lineReader) next() (string, error) {
+	r.line++
+	return readLine(r.reader)
+}
+
+// fail reports err as an InputError on the last line read. Running out of
+// input there means a part of the puzzle is missing.
+func (r *lineReader) fail(field string, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &InputError{Line: r.line, Field: field, Err: err}
+}
+
+// readBoard reads the rows of a board, checking that every tile from 0 to n
+// appears exactly once.
+func (p *puzzleSpec) readBoard(r *lineReader, first string, field string) ([]int, error) {
+	tiles := make([]int, 0, p.rows*p.cols)
+	seen := make([]bool, p.n+1)
+
+	line := first
+	for row := 0; row < p.rows; row++ {
+		rowField := fmt.Sprintf("%s row %d", field, row+1)
+		if row > 0 {
+			var err error
+			if line, err = r.next(); err != nil {
+				return nil, r.fail(rowField, err)
+			}
+		}
+		numbers, err := retrieveNumbers(line, p.cols)
+		if err != nil {
+			return nil, r.fail(rowField, err)
+		}
+		for _, num := range numbers {
+			if num < 0 || num > p.n || seen[num] {
+				return nil, r.fail(rowField, fmt.Errorf("invalid or repeated tile: [%d]", num))
+			}
+			seen[num] = true
+		}
+		tiles = append(tiles, numbers...)
+	}
+
+	return tiles, nil
+}
+
+func (p *PuzzleSolution) Read() error {
+	return p.ReadPuzzle(os.Stdin)
+}
+
+// ReadPuzzle reads a puzzle, and its goal board if there is one, until the
+// end of r. Malformed input is reported as an *InputError.
+func (p *PuzzleSolution) ReadPuzzle(r io.Reader) error {
+	reader, ok := r.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(r)
+	}
+	return p.readFrom(&lineReader{reader: reader})
+}
+
+func (p *PuzzleSolution) readFrom(r *lineReader) error {
+	p.puzzleSpec = &puzzleSpec{}
+
+	line, err := r.next()
+	if err != nil {
+		return r.fail("size", err)
+	}
+	// The header is either the number of tiles of a square board or the
+	// number of rows and columns of a rectangular one.
+	if len(strings.Fields(line)) == 2 {
+		numbers, err := retrieveNumbers(line, 2)
+		if err != nil {
+			return r.fail("size", err)
+		}
+		p.rows, p.cols = numbers[0], numbers[1]
+		p.n = p.rows*p.cols - 1
+	} else {
+		numbers, err := retrieveNumbers(line, 1)
+		if err != nil {
+			return r.fail("size", err)
+		}
+		p.n = numbers[0]
+		p.rows = int(math.Sqrt(float64(p.n + 1)))
+		p.cols = p.rows
+		if p.rows*p.cols != p.n+1 {
+			return r.fail("size", fmt.Errorf("[%d] tiles do not form a square board, give its rows and columns instead", p.n))
+		}
+	}
+	if p.rows <= 0 || p.cols <= 0 {
+		return r.fail("size", fmt.Errorf("invalid board size: [%dx%d]", p.rows, p.cols))
+	}
+
+	line, err = r.next()
+	if err != nil {
+		return r.fail("blank position", err)
+	}
+	numbers, err := retrieveNumbers(line, 1)
+	if err != nil {
+		return r.fail("blank position", err)
+	}
+	zeroIndex, blankLine := numbers[0], r.line
+	if zeroIndex != -1 && (zeroIndex < 1 || zeroIndex > p.n+1) {
+		return r.fail("blank position", fmt.Errorf("blank position [%d] is outside the board", zeroIndex))
+	}
+
+	line, err = r.next()
+	if err != nil {
+		return r.fail("board row 1", err)
+	}
+	tiles, err := p.readBoard(r, line, "board")
+	if err != nil {
+		return err
+	}
+	p.board, err = newPackedBoard(p.rows * p.cols)
+	if err != nil {
+		return &InputError{Line: 1, Field: "size", Err: err}
+	}
+	for idx, tile := range tiles {
+		p.board.set(idx, tile)
+		if tile == 0 {
+			p.currentZero = coordinateFromIndex(p.cols, idx)
+		}
+	}
+
+	// A second board, if present, is the goal. Otherwise the tiles are
+	// expected in order with the blank at the requested position.
+	for line = ""; strings.TrimSpace(line) == "" && err == nil; {
+		line, err = r.next()
+	}
+	if err == io.EOF {
+		if zeroIndex == -1 {
+			zeroIndex = p.n + 1
+		}
+		p.goal = orderedGoal(p.rows, p.cols, zeroIndex-1)
+		p.zeroIndex = p.goal[0]
+		return nil
+	}
+	if err != nil {
+		return r.fail("goal row 1", err)
+	}
+
+	goal, err := p.readBoard(r, line, "goal")
+	if err != nil {
+		return err
+	}
+	p.goal = make([]coordinate, p.n+1)
+	for idx, tile := range goal {
+		p.goal[tile] = coordinateFromIndex(p.cols, idx)
+	}
+	p.zeroIndex = p.goal[0]
+	if zeroIndex != -1 && zeroIndex-1 != p.zeroIndex.toIndex(p.cols) {
+		return &InputError{Line: blankLine, Field: "blank position", Err: fmt.Errorf("blank position [%d] does not match the goal board", zeroIndex)}
+	}
+
+	return nil
+}
+
+// orderedGoal places the tiles in increasing order, skipping the blank cell.
+func orderedGoal(rows, cols int, blank int) []coordinate {
+	goal := make([]coordinate, rows*cols)
+	goal[0] = coordinateFromIndex(cols, blank)
+	for tile := 1; tile < len(goal); tile++ {
+		idx := tile - 1
+		if blank < tile {
+			idx = tile
+		}
+		goal[tile] = coordinateFromIndex(cols, idx)
+	}
+	return goal
+}
+
+func (p *PuzzleSolution) inversionsCount() int {
+	counter := 0
+
+	for i_right := 0; i_right < p.rows; i_right++ {
+		for j_right := 0; j_right < p.cols; j_right++ {
+			if p.tileAt(i_right, j_right) == 0 {
+				continue
+			}
+			for i_left := 0; i_left <= i_right; i_left++ {
+				for j_left := 0; j_left < p.cols; j_left++ {
+					if i_left == i_right && j_left == j_right {
+						break
+					}
+					if p.tileAt(i_left, j_left) == 0 {
+						continue
+					}
+					if p.tileAt(i_left, j_left) > p.tileAt(i_right, j_right) {
+						counter++
+					}
+				}
+			}
+		}
+	}
+
+	return counter
+}
+
+// IsSolvable compares the permutation parity of the board with the one of
+// the goal. A vertical move jumps a tile over cols-1 others, so with an odd
+// width the parity of the inversions never changes, while with an even width
+// it flips together with the parity of the blank row.
+func (p *PuzzleSolution) IsSolvable() bool {
+	goal := p.goalBoard()
+	if p.torus {
+		return p.torusSolvable(goal)
+	}
+
+	// The tiles of a single line can never pass each other.
+	if p.rows == 1 || p.cols == 1 {
+		return sameTileOrder(p, goal)
+	}
+
+	startParity := p.inversionsCount()
+	goalParity := goal.inversionsCount()
+	if p.cols%2 == 0 {
+		startParity += p.currentZero.x
+		goalParity += goal.currentZero.x
+	}
+
+	return startParity%2 == goalParity%2
+}
+
+func sameTileOrder(a, b *PuzzleSolution) bool {
+	var orders [2][]int
+	for k, p := range []*PuzzleSolution{a, b} {
+		for idx := 0; idx < p.rows*p.cols; idx++ {
+			if tile := p.board.get(idx); tile != 0 {
+				orders[k] = append(orders[k], tile)
+			}
+		}
+	}
+
+	for i := range orders[0] {
+		if orders[0][i] != orders[1][i] {
+			return false
+		}
+	}
+	return true
+}
+
+// goalBoard returns the goal configuration as a search root sharing the spec of p.
+func (p *PuzzleSolution) goalBoard() *PuzzleSolution {
+	goal := &PuzzleSolution{puzzleSpec: p.puzzleSpec, currentZero: p.zeroIndex}
+	goal.board, _ = newPackedBoard(p.rows * p.cols)
+	for tile := 1; tile <= p.n; tile++ {
+		goal.board.set(p.goal[tile].toIndex(p.cols), tile)
+	}
+	return goal
+}
+
+func manhattan(p1, p2 coordinate) int {
+	dist := 0
+	if p1.x > p2.x {
+		dist += p1.x - p2.x
+	} else {
+		dist += p2.x - p1.x
+	}
+	if p1.y > p2.y {
+		dist += p1.y - p2.y
+	} else {
+		dist += p2.y - p1.y
+	}
+	return dist
+}
+
+func (p *puzzleSpec) inside(c coordinate) bool {
+	return c.x >= 0 && c.x < p.rows && c.y >= 0 && c.y < p.cols
+}
+
+func (p *PuzzleSolution) tileAt(x, y int) int {
+	return p.board.get(x*p.cols + y)
+}
+
+func (p *PuzzleSolution) estimate() int {
+	return p.estimateState().value
+}
+
+// estimateState returns the heuristic state of p, computing it the first
+// time.
+func (p *PuzzleSolution) estimateState() heuristicState {
+	if p.heuristicKnown {
+		return p.heuristicState
+	}
+
+	if p.heuristic == nil {
+		p.heuristic = manhattanHeuristic{}
+	}
+	p.heuristicState = p.heuristic.Compute(p)
+	p.heuristicKnown = true
+
+	return p.heuristicState
+}
+
+func (p *PuzzleSolution) priority() int {
+	return p.estimate() + p.cost
+}
+
+func (p *PuzzleSolution) move(op operation) (*PuzzleSolution, bool) {
+	if p.parent != nil && p.op == op.opposite() {
+		return nil, false
+	}
+
+	next, ok := p.step(p.currentZero, op)
+	if !ok {
+		return nil, false
+	}
+
+	prev := p.estimateState()
+	newPuzzle := &PuzzleSolution{
+		puzzleSpec:  p.puzzleSpec,
+		board:       p.board.clone(),
+		currentZero: next,
+		parent:      p,
+		op:          op,
+		depth:       p.depth + 1,
+	}
+
+	tile := p.tileAt(next.x, next.y)
+	newPuzzle.cost = p.cost + p.tileCost(tile)
+	newPuzzle.board.swap(p.currentZero.toIndex(p.cols), next.toIndex(p.cols))
+	newPuzzle.heuristicState = p.heuristic.Update(newPuzzle, prev, tile, next, p.currentZero)
+	newPuzzle.heuristicKnown = true
+
+	return newPuzzle, true
+}
+
+func (p *PuzzleSolution) Neighbors() []*PuzzleSolution {
+	var neighs []*PuzzleSolution
+	for _, op := range operations {
+		neigh, ok := p.move(op)
+		if ok {
+			neighs = append(neighs, neigh)
+		}
+	}
+	return neighs
+}
+
+func (p *PuzzleSolution) IsGoal() bool {
+	return p.estimate() == 0 && p.isGoalBoard()
+}
+
+func (p *PuzzleSolution) isGoalBoard() bool {
+	for i := 0; i < p.rows; i++ {
+		for j := 0; j < p.cols; j++ {
+			if p.tileAt(i, j) != 0 && p.goal[p.tileAt(i, j)] != (coordinate{x: i, y: j}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// searchRoot copies p into a fresh root, so that the nodes of a search do not
+// share its board.
+func (p *PuzzleSolution) searchRoot() *PuzzleSolution {
+	return &PuzzleSolution{puzzleSpec: p.puzzleSpec, board: p.board.clone(), currentZero: p.currentZero}
+}
+
+// operations rebuilds the path leading to p from the parent pointers.
+func (p *PuzzleSolution) operations() []operation {
+	ops := make([]operation, p.depth)
+	for node := p; node.parent != nil; node = node.parent {
+		ops[node.depth-1] = node.op
+	}
+	return ops
+}
+
+func (p *PuzzleSolution) toSolution() *solution {
+	return &solution{
+		Operations: p.operations(),
+		Cost:       p.cost,
+	}
+}
+
+// apply slides a tile into the blank in place and returns the tile that moved.
+func (p *PuzzleSolution) apply(op operation) (int, bool) {
+	next, ok := p.step(p.currentZero, op)
+	if !ok {
+		return 0, false
+	}
+
+	tile := p.tileAt(next.x, next.y)
+	p.board.swap(p.currentZero.toIndex(p.cols), next.toIndex(p.cols))
+	p.currentZero = next
+
+	return tile, true
+}
+
+// depthFirstSearch is a single iteration of IDA*. The board of root is
+// modified in place and restored on the way back, while the moves applied on
+// top of it are kept on a stack.
+type depthFirstSearch struct {
+	root   *PuzzleSolution
+	cutOff int
+	weight weighting
+	moves  []operation
+	cost   int
+	budget *searchBudget
+	ctx    context.Context
+	err    error
+
+	// The nodes counted since they were last added to the budget. The path
+	// from the first root, including the nodes above root, is the frontier
+	// of a depth-first search, so peak is also the depth of the deepest
+	// node generated.
+	expanded  int64
+	generated int64
+	peak      int
+
+	// onGoal, when set, is called for every goal found and keeps the search
+	// going as long as it returns true.
+	onGoal func(sol *solution) bool
+}
+
+func (d *depthFirstSearch) lastMove() operation {
+	if len(d.moves) > 0 {
+		return d.moves[len(d.moves)-1]
+	}
+	if d.root.parent != nil {
+		return d.root.op
+	}
+	return ""
+}
+
+func (d *depthFirstSearch) toSolution() *solution {
+	return &solution{
+		Operations: append(d.root.operations(), d.moves...),
+		Cost:       d.root.cost + d.cost,
+	}
+}
+
+// search returns the solution if one fits in the cut-off and otherwise the
+// smallest f-value that exceeded it.
+func (d *depthFirstSearch) search(h heuristicState) (*solution, int) {
+	p := d.root
+	f := d.weight.f(p.cost+d.cost, h.value)
+	if f > d.cutOff {
+		return nil, f
+	}
+	if h.value == 0 && p.isGoalBoard() {
+		sol := d.toSolution()
+		if d.onGoal != nil && d.onGoal(sol) {
+			return nil, f
+		}
+		return sol, f
+	}
+
+	d.expanded++
+	if size := p.depth + len(d.moves) + 1; size > d.peak {
+		d.peak = size
+	}
+	if d.expanded == cancelCheckInterval {
+		if err := d.flush(); err != nil {
+			d.err = err
+			return nil, unreachable
+		}
+	}
+
+	nextCutOff := unreachable
+	for _, op := range operations {
+		if d.lastMove() == op.opposite() {
+			continue
+		}
+
+		oldZero := p.currentZero
+		tile, ok := p.apply(op)
+		if !ok {
+			continue
+		}
+		d.moves = append(d.moves, op)
+		d.cost += p.tileCost(tile)
+		d.generated++
+
+		sol, f := d.search(p.heuristic.Update(p, h, tile, p.currentZero, oldZero))
+
+		d.cost -= p.tileCost(tile)
+		d.moves = d.moves[:len(d.moves)-1]
+		p.apply(op.opposite())
+
+		if sol != nil || d.err != nil {
+			return sol, f
+		}
+		if f < nextCutOff {
+			nextCutOff = f
+		}
+	}
+
+	return nil, nextCutOff
+}
+
+// flush adds the nodes counted since the last call to the budget and checks
+// its limits.
+func (d *depthFirstSearch) flush() error {
+	err := d.budget.add(d.ctx, d.expanded, d.generated, d.peak, d.peak)
+	d.expanded, d.generated, d.peak = 0, 0, 0
+	return err
+}
+
+// run searches from the root and adds what is left to count to the budget
+// once it is done.
+func (d *depthFirstSearch) run() (*solution, int) {
+	sol, f := d.search(d.root.estimateState())
+	if err := d.flush(); err != nil && sol == nil && d.err == nil {
+		d.err = err
+	}
+	return sol, f
+}
+
+func (p *PuzzleSolution) solveWithCutOff(budget *searchBudget, cutOff int, weight weighting) (*solution, int, error) {
+	d := depthFirstSearch{root: p, cutOff: cutOff, weight: weight, budget: budget, ctx: budget.ctx}
+	sol, next := d.run()
+	return sol, next, d.err
+}
+
+const (
+	searchIDA           = "ida"
+	searchBidirectional = "mm"
+	searchAStar         = "astar"
+	searchEES           = "ees"
+	searchTable         = "table"
+	searchConstructive  = "constructive"
+	searchSMA           = "sma"
+)
+
+var searchAlgorithms = []string{searchIDA, searchBidirectional, searchAStar, searchEES, searchTable, searchConstructive, searchSMA}
+
+type solveOptions struct {
+	algorithm string
+	parallel  bool
+	weight    float64
+	tileCosts string
+	torus     bool
+	tableDir  string
+	shorten   bool
+	maxStored int
+	log       io.Writer
+	limits    searchLimits
+	stats     *SearchStats
+}
+
+// solve runs IDA*. With a weight above one every cut-off is at most w times
+// the optimal cost, so the solution is too. The cut-off it is found under
+// also gives a lower bound on the optimal cost, which often proves it closer.
+func (p *PuzzleSolution) solve(budget *searchBudget, weight weighting) (*solution, error) {
+	for cutOff := weight.f(p.cost, p.estimate()); cutOff != unreachable; {
+		budget.prove(weight.lowerBound(cutOff), weight.moves(cutOff))
+		pot, next, err := p.solveWithCutOff(budget, cutOff, weight)
+		if err != nil {
+			return nil, err
+		}
+		if pot != nil {
+			pot.Bound = provenBound(pot.Cost, weight.lowerBound(cutOff), weight)
+			return pot, nil
+		}
+		cutOff = next
+	}
+	return nil, nil
+}
+
+// solveContext searches for a solution until ctx is done or one of the limits
+// in opts is hit, in which case a *LimitError is returned. It returns a nil
+// solution and error when the search space is exhausted. The statistics of
+// every iteration are stored in opts.stats when it is set.
+func (p *PuzzleSolution) solveContext(ctx context.Context, opts solveOptions) (*solution, error) {
+	if opts.limits.timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.limits.timeLimit)
+		defer cancel()
+	}
+	if err := p.setTileCosts(opts.tileCosts); err != nil {
+		return nil, err
+	}
+	p.torus = opts.torus
+	// The search starts from a fresh node, as an estimate p cached under a
+	// previous heuristic, torus or tile costs no longer holds.
+	p = p.searchRoot()
+	budget := newSearchBudget(ctx, opts.limits)
+	weight := newWeighting(opts.weight)
+
+	var (
+		pot *solution
+		err error
+	)
+	switch {
+	case opts.algorithm == searchBidirectional:
+		pot, err = p.solveBidirectional(budget)
+	case opts.algorithm == searchAStar:
+		pot, err = p.solveWeightedAStar(budget, weight)
+	case opts.algorithm == searchEES:
+		pot, err = p.solveExplicitEstimation(budget, weight)
+	case opts.algorithm == searchTable:
+		pot, err = p.solveFromTable(opts.tableDir, opts.log)
+	case opts.algorithm == searchConstructive:
+		pot, err = p.solveConstructive(opts.shorten)
+	case opts.algorithm == searchSMA:
+		pot, err = p.solveSMA(budget, weight, opts.maxStored)
+	case opts.parallel:
+		pot, err = p.solveParallel(budget, runtime.GOMAXPROCS(0), weight)
+	default:
+		pot, err = p.solve(budget, weight)
+	}
+
+	if opts.stats != nil {
+		budget.finishIteration()
+		opts.stats.Algorithm = opts.algorithm
+		opts.stats.Heuristic = p.heuristic.Name()
+		opts.stats.Iterations = budget.iterations
+	}
+	return pot, err
+}
+
+// solveAndPrint solves p and prints the running time and the solution, the
+// way the command line does.
+func (p *PuzzleSolution) solveAndPrint(opts solveOptions, table bool, tracePath string, checkTable bool) error {
+	startTime := time.Now()
+
+	if table || tracePath != "" {
+		opts.stats = &SearchStats{}
+	}
+	pot, err := p.solveContext(context.Background(), opts)
+	if table {
+		opts.stats.PrintTable(os.Stderr)
+	}
+	if tracePath != "" {
+		if err := opts.stats.WriteTrace(tracePath); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if pot == nil {
+		fmt.Println("no solution found...")
+		return nil
+	}
+
+	dur := time.Since(startTime)
+	fmt.Printf("%.3f\n", dur.Seconds())
+	pot.Print()
+	if checkTable {
+		return p.checkAgainstTable(pot, opts.tableDir)
+	}
+	return nil
+}
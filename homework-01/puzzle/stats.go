@@ -0,0 +1,97 @@
+package puzzle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// IterationStats describes a single cut-off of a search: an IDA* iteration,
+// or the nodes a best-first search expanded at that f-value.
+type IterationStats struct {
+	CutOff          int     `json:"cutOff"`
+	LowerBound      int     `json:"lowerBound"`
+	Generated       int64   `json:"generated"`
+	Expanded        int64   `json:"expanded"`
+	PeakFrontier    int64   `json:"peakFrontier"`
+	Depth           int     `json:"depth"`
+	BranchingFactor float64 `json:"branchingFactor"`
+	Seconds         float64 `json:"seconds"`
+}
+
+type SearchStats struct {
+	Algorithm  string           `json:"algorithm"`
+	Heuristic  string           `json:"heuristic"`
+	Iterations []IterationStats `json:"iterations"`
+}
+
+// effectiveBranchingFactor is the b for which a uniform tree of the given
+// depth has as many nodes as were generated: b + b^2 + ... + b^depth.
+func effectiveBranchingFactor(generated int64, depth int) float64 {
+	if generated <= 0 || depth <= 0 {
+		return 0
+	}
+
+	nodes := func(b float64) float64 {
+		sum, level := 0.0, 1.0
+		for i := 0; i < depth && sum <= float64(generated); i++ {
+			level *= b
+			sum += level
+		}
+		return sum
+	}
+
+	low, high := 0.0, float64(generated)
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if nodes(mid) < float64(generated) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+func (s *SearchStats) total() IterationStats {
+	var total IterationStats
+	for _, it := range s.Iterations {
+		total.CutOff = it.CutOff
+		total.LowerBound = it.LowerBound
+		total.Generated += it.Generated
+		total.Expanded += it.Expanded
+		if it.PeakFrontier > total.PeakFrontier {
+			total.PeakFrontier = it.PeakFrontier
+		}
+		if it.Depth > total.Depth {
+			total.Depth = it.Depth
+		}
+		total.Seconds += it.Seconds
+	}
+	total.BranchingFactor = effectiveBranchingFactor(total.Generated, total.Depth)
+	return total
+}
+
+func (s *SearchStats) PrintTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "cut-off\tlower bound\tgenerated\texpanded\tpeak frontier\tdepth\tbranching\ttime\t\n")
+	row := func(name string, it IterationStats) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%.3f\t%.3f\t\n",
+			name, it.LowerBound, it.Generated, it.Expanded, it.PeakFrontier, it.Depth, it.BranchingFactor, it.Seconds)
+	}
+	for _, it := range s.Iterations {
+		row(fmt.Sprint(it.CutOff), it)
+	}
+	row("total", s.total())
+	w.Flush()
+}
+
+func (s *SearchStats) WriteTrace(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,346 @@
+package puzzle
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+const weightPrecision = 100
+
+// weighting is a heuristic weight kept as a fraction, so that f-values stay
+// integers: f = den*g + num*h.
+type weighting struct {
+	num int
+	den int
+}
+
+func newWeighting(w float64) weighting {
+	if w <= 1 {
+		return weighting{num: 1, den: 1}
+	}
+	return weighting{num: int(math.Round(w * weightPrecision)), den: weightPrecision}
+}
+
+func (w weighting) f(g, h int) int {
+	if w.den == 0 {
+		return g + h
+	}
+	return w.den*g + w.num*h
+}
+
+// moves converts an f-value back to moves, i.e. to g + w*h.
+func (w weighting) moves(f int) int {
+	if w.den == 0 {
+		return f
+	}
+	return f / w.den
+}
+
+// lowerBound is the least cost of a solution once nothing with a smaller
+// f-value is left, as every node on an optimal path has f at most num times
+// that cost.
+func (w weighting) lowerBound(f int) int {
+	if w.num == 0 {
+		return f
+	}
+	return (f + w.num - 1) / w.num
+}
+
+// within reports whether cost is at most w times bound.
+func (w weighting) within(cost, bound int) bool {
+	return w.den*cost <= w.num*bound
+}
+
+func (w weighting) bound() float64 {
+	if w.den == 0 || w.num == w.den {
+		return 0
+	}
+	return float64(w.num) / float64(w.den)
+}
+
+func validateSearch(algorithm string, weight float64) error {
+	found := false
+	for _, name := range searchAlgorithms {
+		found = found || name == algorithm
+	}
+	if !found {
+		return fmt.Errorf("unknown search algorithm: [%s]", algorithm)
+	}
+	if weight < 1 {
+		return fmt.Errorf("weight must be at least 1, found: [%v]", weight)
+	}
+	return nil
+}
+
+// provenBound divides the cost of a solution by a lower bound on the optimal
+// one, falling back to the weight the search guarantees.
+func provenBound(cost, lowerBound int, weight weighting) float64 {
+	bound := weight.bound()
+	if bound == 0 {
+		return 0
+	}
+	if lowerBound > 0 && float64(cost)/float64(lowerBound) < bound {
+		return float64(cost) / float64(lowerBound)
+	}
+	return bound
+}
+
+// solveWeightedAStar expands nodes by g + w*h, reopening nodes reached by a
+// cheaper path. The smallest g + h on the open list is a lower bound on the
+// optimal cost, which gives a proven bound often well below w.
+func (p *PuzzleSolution) solveWeightedAStar(budget *searchBudget, weight weighting) (*solution, error) {
+	open := newSearchFrontier(p.searchRoot(), func(node *PuzzleSolution) int {
+		return weight.f(node.cost, node.estimate())
+	})
+
+	for open.open > 0 {
+		lowerBound := firstNonEmpty(open.fCount)
+		budget.prove(lowerBound, weight.moves(open.peekPriority()))
+		node := open.pop()
+		if node.IsGoal() {
+			sol := node.toSolution()
+			sol.Bound = provenBound(node.cost, lowerBound, weight)
+			return sol, nil
+		}
+		if err := budget.expand(budget.ctx); err != nil {
+			return nil, err
+		}
+
+		children := node.Neighbors()
+		budget.generate(len(children), node.depth+1)
+		for _, child := range children {
+			if entry, ok := open.nodes[child.board.key()]; ok && entry.node.cost <= child.cost {
+				continue
+			}
+			open.push(child)
+		}
+		budget.frontier(open.open)
+	}
+
+	return nil, nil
+}
+
+// eesNode carries the inadmissible estimates of explicit estimation search:
+// fHat = g + hHat of the total cost and dHat of the moves left.
+type eesNode struct {
+	*PuzzleSolution
+	fHat   int
+	dHat   float64
+	closed bool
+}
+
+type eesItem struct {
+	record *eesNode
+	node   *PuzzleSolution
+}
+
+func (it eesItem) valid() bool {
+	return !it.record.closed && it.record.PuzzleSolution == it.node
+}
+
+type eesHeap []eesItem
+
+func (h eesHeap) Len() int {
+	return len(h)
+}
+
+func (h eesHeap) Less(i, j int) bool {
+	return h[i].record.dHat < h[j].record.dHat
+}
+
+func (h eesHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *eesHeap) Push(x interface{}) {
+	*h = append(*h, x.(eesItem))
+}
+
+func (h *eesHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// explicitEstimation is the state of explicit estimation search (Thayer and
+// Ruml, 2011). Open nodes are bucketed by fHat, each bucket ordered by dHat,
+// so the focal list is the union of the buckets up to w times the smallest
+// fHat. The cleanup list buckets them by their admissible f.
+type explicitEstimation struct {
+	weight  weighting
+	records map[boardKey]*eesNode
+	byFHat  []eesHeap
+	byF     [][]eesItem
+	minFHat int
+	minF    int
+	open    int
+
+	errorH  float64
+	errorD  float64
+	samples int
+}
+
+func (e *explicitEstimation) push(node *PuzzleSolution) {
+	key := node.board.key()
+	record, ok := e.records[key]
+	if ok && record.cost <= node.cost {
+		return
+	}
+	if !ok {
+		record = &eesNode{closed: true}
+		e.records[key] = record
+	}
+	if record.closed {
+		e.open++
+	}
+
+	// One-step error correction: every move is expected to miss the
+	// heuristic by the average error observed so far.
+	h := float64(node.estimate())
+	errorD := e.errorD
+	if errorD > 0.99 {
+		errorD = 0.99
+	}
+	record.PuzzleSolution = node
+	record.closed = false
+	record.dHat = h / (1 - errorD)
+	record.fHat = node.cost + int(math.Round(h+e.errorH*record.dHat))
+
+	item := eesItem{record: record, node: node}
+	for len(e.byFHat) <= record.fHat {
+		e.byFHat = append(e.byFHat, nil)
+	}
+	heap.Push(&e.byFHat[record.fHat], item)
+	if record.fHat < e.minFHat {
+		e.minFHat = record.fHat
+	}
+
+	f := node.priority()
+	for len(e.byF) <= f {
+		e.byF = append(e.byF, nil)
+	}
+	e.byF[f] = append(e.byF[f], item)
+	if f < e.minF {
+		e.minF = f
+	}
+}
+
+func (e *explicitEstimation) bestFHatTop(fHat int) (eesItem, bool) {
+	if fHat >= len(e.byFHat) {
+		return eesItem{}, false
+	}
+	bucket := &e.byFHat[fHat]
+	for bucket.Len() > 0 && !(*bucket)[0].valid() {
+		heap.Pop(bucket)
+	}
+	if bucket.Len() == 0 {
+		return eesItem{}, false
+	}
+	return (*bucket)[0], true
+}
+
+func (e *explicitEstimation) bestF() (eesItem, bool) {
+	for ; e.minF < len(e.byF); e.minF++ {
+		bucket := e.byF[e.minF]
+		for len(bucket) > 0 && !bucket[len(bucket)-1].valid() {
+			bucket = bucket[:len(bucket)-1]
+		}
+		e.byF[e.minF] = bucket
+		if len(bucket) > 0 {
+			return bucket[len(bucket)-1], true
+		}
+	}
+	return eesItem{}, false
+}
+
+// selectNode picks the node with the fewest moves left among those whose
+// estimated cost is within w times the smallest one, then the one with the
+// smallest estimated cost, as long as that estimate is within w times the
+// smallest admissible f-value; otherwise it raises the lower bound instead.
+func (e *explicitEstimation) selectNode() (*PuzzleSolution, int, bool) {
+	cleanup, ok := e.bestF()
+	if !ok {
+		return nil, 0, false
+	}
+	lowerBound := cleanup.node.priority()
+
+	for ; e.minFHat < len(e.byFHat); e.minFHat++ {
+		if _, ok := e.bestFHatTop(e.minFHat); ok {
+			break
+		}
+	}
+	bestFHat, _ := e.bestFHatTop(e.minFHat)
+
+	bestD := bestFHat
+	for fHat := e.minFHat + 1; fHat < len(e.byFHat) && e.weight.within(fHat, e.minFHat); fHat++ {
+		if top, ok := e.bestFHatTop(fHat); ok && top.record.dHat < bestD.record.dHat {
+			bestD = top
+		}
+	}
+
+	chosen := cleanup
+	if e.weight.within(bestD.record.fHat, lowerBound) {
+		chosen = bestD
+	} else if e.weight.within(bestFHat.record.fHat, lowerBound) {
+		chosen = bestFHat
+	}
+	chosen.record.closed = true
+	e.open--
+	return chosen.node, lowerBound, true
+}
+
+// learn measures the one-step errors of the expansion of node, against its
+// child with the smallest f-value: how much more the cost estimate of the
+// child is, and how many more moves it leaves than one fewer than node.
+func (e *explicitEstimation) learn(node *PuzzleSolution, children []*PuzzleSolution) {
+	var best *PuzzleSolution
+	for _, child := range children {
+		if best == nil || child.priority() < best.priority() {
+			best = child
+		}
+	}
+	if best == nil {
+		return
+	}
+
+	errorH := float64(best.priority() - node.priority())
+	errorD := float64(best.estimate() + 1 - node.estimate())
+	e.samples++
+	e.errorH += (errorH - e.errorH) / float64(e.samples)
+	e.errorD += (errorD - e.errorD) / float64(e.samples)
+}
+
+// solveExplicitEstimation finds a solution costing at most w times the
+// optimum, using inadmissible estimates to pick which node to expand and the
+// admissible heuristic only to keep the bound proven.
+func (p *PuzzleSolution) solveExplicitEstimation(budget *searchBudget, weight weighting) (*solution, error) {
+	e := &explicitEstimation{weight: weight, records: make(map[boardKey]*eesNode)}
+	e.push(p.searchRoot())
+
+	for {
+		node, lowerBound, ok := e.selectNode()
+		if !ok {
+			return nil, nil
+		}
+		budget.prove(lowerBound, lowerBound)
+		if node.IsGoal() {
+			sol := node.toSolution()
+			sol.Bound = provenBound(node.cost, lowerBound, weight)
+			return sol, nil
+		}
+		if err := budget.expand(budget.ctx); err != nil {
+			return nil, err
+		}
+
+		children := node.Neighbors()
+		budget.generate(len(children), node.depth+1)
+		e.learn(node, children)
+		for _, child := range children {
+			e.push(child)
+		}
+		budget.frontier(e.open)
+	}
+}
@@ -0,0 +1,311 @@
+package puzzle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	stateTableMagic    = "SPT1"
+	stateTableMaxCells = 9
+	stateTableUnseen   = uint8(0xff)
+)
+
+// stateTable holds the distance to the goal of every board of a small puzzle,
+// indexed by the rank of the permutation of its cells. Half of the entries,
+// the unsolvable boards, are never reached.
+type stateTable struct {
+	rows  int
+	cols  int
+	torus bool
+	goal  []int
+	dist  []uint8
+}
+
+func newStateTable(p *PuzzleSolution) (*stateTable, error) {
+	cells := p.rows * p.cols
+	if cells > stateTableMaxCells {
+		return nil, fmt.Errorf("complete tables are only built for boards of up to [%d] cells", stateTableMaxCells)
+	}
+
+	t := &stateTable{rows: p.rows, cols: p.cols, torus: p.torus, goal: make([]int, cells)}
+	for tile, c := range p.goal {
+		t.goal[c.toIndex(p.cols)] = tile
+	}
+	return t, nil
+}
+
+func factorial(n int) int {
+	res := 1
+	for i := 2; i <= n; i++ {
+		res *= i
+	}
+	return res
+}
+
+func (t *stateTable) rank(board *packedBoard) int {
+	cells := len(t.goal)
+	res := 0
+	for i := 0; i < cells; i++ {
+		smaller := 0
+		for j := i + 1; j < cells; j++ {
+			if board.get(j) < board.get(i) {
+				smaller++
+			}
+		}
+		res = res*(cells-i) + smaller
+	}
+	return res
+}
+
+func (t *stateTable) unrank(rank int, board *packedBoard) {
+	cells := len(t.goal)
+	digits := make([]int, cells)
+	for i := cells - 1; i >= 0; i-- {
+		digits[i] = rank % (cells - i)
+		rank /= cells - i
+	}
+
+	var used [stateTableMaxCells]bool
+	for i, digit := range digits {
+		for tile := 0; ; tile++ {
+			if used[tile] {
+				continue
+			}
+			if digit == 0 {
+				board.set(i, tile)
+				used[tile] = true
+				break
+			}
+			digit--
+		}
+	}
+}
+
+// build runs a breadth-first search from the goal over every board.
+func (t *stateTable) build(p *PuzzleSolution) {
+	t.dist = make([]uint8, factorial(len(t.goal)))
+	for i := range t.dist {
+		t.dist[i] = stateTableUnseen
+	}
+
+	node := p.goalBoard()
+	start := t.rank(&node.board)
+	t.dist[start] = 0
+	queue := []int{start}
+
+	for len(queue) > 0 {
+		rank := queue[0]
+		queue = queue[1:]
+
+		t.unrank(rank, &node.board)
+		for idx := range t.goal {
+			if node.board.get(idx) == 0 {
+				node.currentZero = coordinateFromIndex(t.cols, idx)
+			}
+		}
+
+		for _, op := range operations {
+			if _, ok := node.apply(op); !ok {
+				continue
+			}
+			if next := t.rank(&node.board); t.dist[next] == stateTableUnseen {
+				t.dist[next] = t.dist[rank] + 1
+				queue = append(queue, next)
+			}
+			node.apply(op.opposite())
+		}
+	}
+}
+
+func (t *stateTable) header() []uint16 {
+	torus := uint16(0)
+	if t.torus {
+		torus = 1
+	}
+	header := []uint16{uint16(t.rows), uint16(t.cols), torus}
+	for _, tile := range t.goal {
+		header = append(header, uint16(tile))
+	}
+	return header
+}
+
+func (t *stateTable) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(stateTableMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, t.header()); err != nil {
+		return err
+	}
+	if _, err := w.Write(t.dist); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (t *stateTable) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	expected := t.header()
+	headerLen := len(stateTableMagic) + 2*len(expected)
+	if len(data) != headerLen+factorial(len(t.goal)) || string(data[:len(stateTableMagic)]) != stateTableMagic {
+		return fmt.Errorf("state table [%s] is malformed", path)
+	}
+
+	found := make([]uint16, len(expected))
+	if err := binary.Read(bytes.NewReader(data[len(stateTableMagic):headerLen]), binary.LittleEndian, found); err != nil {
+		return err
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			return fmt.Errorf("state table [%s] was built for a different puzzle", path)
+		}
+	}
+
+	t.dist = data[headerLen:]
+	return nil
+}
+
+func (t *stateTable) fileName() string {
+	goal := make([]string, len(t.goal))
+	for i, tile := range t.goal {
+		goal[i] = strconv.Itoa(tile)
+	}
+	kind := "plain"
+	if t.torus {
+		kind = "torus"
+	}
+	return fmt.Sprintf("table-%dx%d-%s-%s.bin", t.rows, t.cols, kind, strings.Join(goal, "_"))
+}
+
+// loadStateTable loads the table for the size and goal of p from dir, or
+// builds and stores it the first time, saying so on log unless it is nil.
+func (p *PuzzleSolution) loadStateTable(dir string, log io.Writer) (*stateTable, error) {
+	t, err := newStateTable(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, t.fileName())
+	err = t.load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if log != nil {
+			fmt.Fprintf(log, "building state table [%s]...\n", path)
+		}
+		t.build(p)
+		err = t.save(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// distance is the optimal number of moves from p to the goal, or -1 when the
+// goal cannot be reached.
+func (t *stateTable) distance(p *PuzzleSolution) int {
+	d := t.dist[t.rank(&p.board)]
+	if d == stateTableUnseen {
+		return -1
+	}
+	return int(d)
+}
+
+// solve walks down the distances from p, one move at a time.
+func (t *stateTable) solve(p *PuzzleSolution) *solution {
+	d := t.distance(p)
+	if d < 0 {
+		return nil
+	}
+
+	node := p.searchRoot()
+	sol := &solution{Cost: d}
+	for ; d > 0; d-- {
+		for _, op := range operations {
+			if _, ok := node.apply(op); !ok {
+				continue
+			}
+			if t.distance(node) == d-1 {
+				sol.Operations = append(sol.Operations, op)
+				break
+			}
+			node.apply(op.opposite())
+		}
+	}
+	return sol
+}
+
+func (p *PuzzleSolution) solveFromTable(dir string, log io.Writer) (*solution, error) {
+	if p.costs != nil {
+		return nil, fmt.Errorf("the state table counts moves, it cannot be used with tile costs")
+	}
+	t, err := p.loadStateTable(dir, log)
+	if err != nil {
+		return nil, err
+	}
+	return t.solve(p), nil
+}
+
+// checkAgainstTable reports on stderr whether sol is as short as the optimal
+// solution from the state table, which makes the table a reference for the
+// other searches.
+func (p *PuzzleSolution) checkAgainstTable(sol *solution, dir string) error {
+	t, err := p.loadStateTable(dir, os.Stderr)
+	if err != nil {
+		return err
+	}
+	optimal := t.distance(p)
+	if len(sol.Operations) == optimal {
+		fmt.Fprintf(os.Stderr, "optimal according to the state table: %d moves\n", optimal)
+	} else {
+		fmt.Fprintf(os.Stderr, "not optimal, the state table needs %d moves instead of %d\n", optimal, len(sol.Operations))
+	}
+	return nil
+}
+
+// BuildStateTable builds and stores the table of the rows x cols puzzle with
+// the tiles in order and the blank last, unless it is already in dir.
+func BuildStateTable(rows, cols int, torus bool, dir string) error {
+	p := PuzzleSolution{}
+	if err := p.newEmptyPuzzle(rows, cols); err != nil {
+		return err
+	}
+	p.torus = torus
+	t, err := p.loadStateTable(dir, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	reachable := 0
+	for _, d := range t.dist {
+		if d != stateTableUnseen {
+			reachable++
+		}
+	}
+	fmt.Printf("%s: %d reachable boards\n", filepath.Join(dir, t.fileName()), reachable)
+	return nil
+}
@@ -0,0 +1,152 @@
+package puzzle
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// tablePuzzle returns the rows x cols puzzle whose goal has the tiles in order
+// and the blank in cell blank, together with its complete state table.
+func tablePuzzle(t *testing.T, rows, cols, blank int, torus bool) (*PuzzleSolution, *stateTable) {
+	t.Helper()
+	p := &PuzzleSolution{}
+	if err := p.newEmptyPuzzle(rows, cols); err != nil {
+		t.Fatal(err)
+	}
+	p.goal = orderedGoal(rows, cols, blank)
+	p.zeroIndex = p.goal[0]
+	p.currentZero = p.zeroIndex
+	p.board = p.goalBoard().board
+	p.torus = torus
+
+	table, err := newStateTable(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.build(p)
+	return p, table
+}
+
+// boardAt returns the board of p with the given rank in table.
+func boardAt(p *PuzzleSolution, table *stateTable, rank int) *PuzzleSolution {
+	board := p.searchRoot()
+	table.unrank(rank, &board.board)
+	for idx := 0; idx < p.rows*p.cols; idx++ {
+		if board.board.get(idx) == 0 {
+			board.currentZero = coordinateFromIndex(p.cols, idx)
+		}
+	}
+	return board
+}
+
+// solvableBoards picks count boards of p at random among those the table
+// reaches.
+func solvableBoards(p *PuzzleSolution, table *stateTable, count int, seed int64) []*PuzzleSolution {
+	rnd := rand.New(rand.NewSource(seed))
+	var boards []*PuzzleSolution
+	for len(boards) < count {
+		rank := rnd.Intn(len(table.dist))
+		if table.dist[rank] != stateTableUnseen {
+			boards = append(boards, boardAt(p, table, rank))
+		}
+	}
+	return boards
+}
+
+// replay applies the moves of sol to p and reports whether they reach the
+// goal.
+func replay(p *PuzzleSolution, sol *solution) bool {
+	board := p.searchRoot()
+	for _, op := range sol.Operations {
+		if _, ok := board.apply(op); !ok {
+			return false
+		}
+	}
+	return board.isGoalBoard()
+}
+
+func TestSearchesMatchStateTable(t *testing.T) {
+	searches := []struct {
+		algorithm string
+		parallel  bool
+	}{
+		{algorithm: searchIDA},
+		{algorithm: searchIDA, parallel: true},
+		{algorithm: searchBidirectional},
+		{algorithm: searchAStar},
+		{algorithm: searchEES},
+		{algorithm: searchSMA},
+	}
+
+	for _, size := range [][2]int{{3, 3}, {2, 4}} {
+		p, table := tablePuzzle(t, size[0], size[1], size[0]*size[1]-1, false)
+		boards := solvableBoards(p, table, 20, 1)
+
+		for _, name := range heuristicNames() {
+			h, err := p.loadHeuristic(heuristicOptions{name: name, pdbDir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.heuristic = h
+
+			for _, search := range searches {
+				opts := solveOptions{algorithm: search.algorithm, parallel: search.parallel, weight: 1, maxStored: defaultMaxStored}
+				for _, board := range boards {
+					sol, err := board.searchRoot().solveContext(context.Background(), opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want := table.distance(board)
+					if sol == nil || sol.Cost != want || len(sol.Operations) != want || !replay(board, sol) {
+						t.Errorf("%dx%d %s %s parallel=%v: got %+v, want an optimal solution of %d moves",
+							size[0], size[1], search.algorithm, name, search.parallel, sol, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestIsSolvableMatchesStateTable(t *testing.T) {
+	for _, size := range [][2]int{{3, 3}, {2, 4}, {2, 3}, {3, 2}, {1, 4}} {
+		for _, torus := range []bool{false, true} {
+			for _, blank := range []int{0, size[0]*size[1] - 1} {
+				t.Run(fmt.Sprintf("%dx%d/torus=%v/blank=%d", size[0], size[1], torus, blank), func(t *testing.T) {
+					p, table := tablePuzzle(t, size[0], size[1], blank, torus)
+					for rank := range table.dist {
+						board := boardAt(p, table, rank)
+						if reached := table.dist[rank] != stateTableUnseen; board.IsSolvable() != reached {
+							t.Fatalf("board of rank %d: IsSolvable is %v, the table says %v", rank, !reached, reached)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestSolveAgainWithAnotherHeuristic solves the same node with one heuristic
+// and then another, which must not reuse the estimate cached by the first.
+func TestSolveAgainWithAnotherHeuristic(t *testing.T) {
+	p, table := tablePuzzle(t, 3, 3, 8, false)
+	board := solvableBoards(p, table, 1, 3)[0]
+	want := table.distance(board)
+
+	for _, name := range []string{patternDatabaseName, manhattanHeuristic{}.Name()} {
+		h, err := p.loadHeuristic(heuristicOptions{name: name, pdbDir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.heuristic = h
+		opts := solveOptions{algorithm: searchIDA, parallel: true, weight: 1, maxStored: defaultMaxStored}
+		sol, err := board.solveContext(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sol == nil || sol.Cost != want {
+			t.Fatalf("%s: got %+v, want a solution of %d moves", name, sol, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package puzzle
+
+// step returns the cell the blank reaches from c when a tile moves by op. On
+// a torus it wraps around the edges, except along a dimension of two cells,
+// where the wrapped cell is the adjacent one anyway.
+func (p *puzzleSpec) step(c coordinate, op operation) (coordinate, bool) {
+	shift := op.blankShift()
+	next := coordinate{x: c.x + shift.x, y: c.y + shift.y}
+	if p.inside(next) {
+		return next, true
+	}
+	if !p.torus || (shift.x != 0 && p.rows <= 2) || (shift.y != 0 && p.cols <= 2) {
+		return next, false
+	}
+	return coordinate{x: (next.x + p.rows) % p.rows, y: (next.y + p.cols) % p.cols}, true
+}
+
+// distance is the manhattan distance, going around the edges of a torus
+// when that is shorter.
+func (p *puzzleSpec) distance(a, b coordinate) int {
+	if !p.torus {
+		return manhattan(a, b)
+	}
+	return wrapped(a.x, b.x, p.rows) + wrapped(a.y, b.y, p.cols)
+}
+
+func wrapped(a, b, size int) int {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	if size-d < d {
+		return size - d
+	}
+	return d
+}
+
+// torusHeuristic tells whether the heuristic stays admissible on a torus.
+// Linear conflicts and pattern databases assume tiles cannot go around the
+// edges.
+func torusHeuristic(name string) bool {
+	switch name {
+	case manhattanHeuristic{}.Name(), weightedManhattanHeuristic{}.Name(), misplacedTilesHeuristic{}.Name():
+		return true
+	}
+	return false
+}
+
+// torusSolvable decides solvability on a torus. Going around a line of odd
+// length moves the blank back to its cell with an odd permutation of the
+// tiles, so then every board is solvable. With both dimensions even, wrapping
+// keeps the colour of the blank on a checkerboard, so the parity argument of
+// the plain board still holds, and on a single line only the cyclic order of
+// the tiles matters.
+func (p *PuzzleSolution) torusSolvable(goal *PuzzleSolution) bool {
+	if p.rows == 1 || p.cols == 1 {
+		if p.rows*p.cols <= 2 {
+			return sameTileOrder(p, goal)
+		}
+		return sameCyclicOrder(p, goal)
+	}
+	if p.rows%2 == 1 || p.cols%2 == 1 {
+		return true
+	}
+
+	plain := *p.puzzleSpec
+	plain.torus = false
+	start := &PuzzleSolution{puzzleSpec: &plain, board: p.board, currentZero: p.currentZero}
+	return start.IsSolvable()
+}
+
+func sameCyclicOrder(a, b *PuzzleSolution) bool {
+	var orders [2][]int
+	for k, p := range []*PuzzleSolution{a, b} {
+		for idx := 0; idx < p.rows*p.cols; idx++ {
+			if tile := p.board.get(idx); tile != 0 {
+				orders[k] = append(orders[k], tile)
+			}
+		}
+	}
+
+	n := len(orders[0])
+	for shift := 0; shift < n; shift++ {
+		same := true
+		for i := 0; i < n && same; i++ {
+			same = orders[0][(i+shift)%n] == orders[1][i]
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
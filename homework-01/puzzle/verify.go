@@ -0,0 +1,125 @@
+package puzzle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const boundPrefix = "suboptimality bound:"
+
+func parseOperation(s string) (operation, bool) {
+	for _, op := range operations {
+		if string(op) == s {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// readSolution reads a move list as printed by solution.Print. The running
+// time printed by Solve before it is skipped, so the whole output of a solve
+// can be checked as is.
+func readSolution(reader *bufio.Reader) (*solution, error) {
+	var lines []string
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > 0 && strings.Contains(lines[0], ".") {
+		if _, err := strconv.ParseFloat(lines[0], 64); err == nil {
+			lines = lines[1:]
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("the move list is empty")
+	}
+	count, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("expected the number of moves, found: [%s]", lines[0])
+	}
+	lines = lines[1:]
+	for _, prefix := range []string{boundPrefix, costPrefix} {
+		if len(lines) > 0 && strings.HasPrefix(lines[len(lines)-1], prefix) {
+			lines = lines[:len(lines)-1]
+		}
+	}
+	if len(lines) != count {
+		return nil, fmt.Errorf("expected move count: [%d], found count: [%d]", count, len(lines))
+	}
+
+	sol := &solution{Operations: make([]operation, count)}
+	for i, line := range lines {
+		op, ok := parseOperation(line)
+		if !ok {
+			return nil, fmt.Errorf("unknown move [%d]: [%s]", i+1, line)
+		}
+		sol.Operations[i] = op
+	}
+	return sol, nil
+}
+
+func readSolutionFile(path string) (*solution, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSolution(bufio.NewReader(f))
+}
+
+type verification struct {
+	moves   int
+	illegal int
+	op      operation
+	blank   coordinate
+	solved  bool
+}
+
+// verify replays the moves on a copy of p. Unlike the search, which never
+// undoes its last move, any move keeping the blank on the board is legal.
+func (p *PuzzleSolution) verify(sol *solution) verification {
+	board := p.searchRoot()
+	res := verification{moves: len(sol.Operations)}
+
+	for i, op := range sol.Operations {
+		if _, ok := board.apply(op); !ok {
+			res.illegal = i + 1
+			res.op = op
+			res.blank = board.currentZero
+			return res
+		}
+	}
+
+	res.solved = board.isGoalBoard()
+	return res
+}
+
+func (v verification) ok() bool {
+	return v.illegal == 0 && v.solved
+}
+
+func (v verification) Print() {
+	if v.illegal != 0 {
+		fmt.Printf("illegal move [%d]: [%s] with the blank at row [%d], column [%d]\n", v.illegal, v.op, v.blank.x+1, v.blank.y+1)
+		return
+	}
+	fmt.Printf("all [%d] moves are legal\n", v.moves)
+	if v.solved {
+		fmt.Println("goal reached")
+	} else {
+		fmt.Println("goal not reached...")
+	}
+}
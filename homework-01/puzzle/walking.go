@@ -0,0 +1,239 @@
+package puzzle
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const (
+	walkingDistanceName   = "walking-distance"
+	walkingDistanceLCName = "walking-distance-lc"
+
+	// walkingMaxStates keeps the tables to the sizes up to the 15-puzzle and
+	// its rectangular neighbours. The 24-puzzle already has tens of millions.
+	walkingMaxStates = 1 << 22
+)
+
+// walkingTable holds the walking distance along one dimension: the number of
+// moves needed to bring every tile to its goal line when only the line of
+// each tile and the line it belongs to are known. A state counts, for every
+// line, how many of its tiles belong to each line, and where the blank is.
+// States are numbered in the order they are found, index giving the number
+// of a packed key. Moving the blank to the line before (0) or after (1) its
+// own, taking a tile belonging to goal from there, leads from state s to
+// state next[(2*s+dir)*lines+goal], which is -1 when there is no such tile.
+type walkingTable struct {
+	lines      int
+	countBits  uint
+	blankBits  uint
+	blankGoal  int
+	goalCounts []int
+	index      map[uint64]int32
+	dist       []uint8
+	next       []int32
+}
+
+// newWalkingTable runs a breadth-first search from the goal state of lines
+// lines of length cells each, the blank belonging to blankGoal.
+func newWalkingTable(lines, length, blankGoal int) (*walkingTable, error) {
+	t := &walkingTable{
+		lines:     lines,
+		countBits: uint(bits.Len(uint(length))),
+		blankBits: uint(bits.Len(uint(lines - 1))),
+		blankGoal: blankGoal,
+	}
+	// The last line follows from the others, so it is left out of the key.
+	if t.blankBits+t.countBits*uint((lines-1)*lines) > 64 {
+		return nil, fmt.Errorf("walking distance is not supported for boards with [%d] lines of [%d] cells", lines, length)
+	}
+
+	t.goalCounts = make([]int, lines*lines)
+	for line := 0; line < lines; line++ {
+		t.goalCounts[line*lines+line] = length
+	}
+	t.goalCounts[blankGoal*lines+blankGoal]--
+
+	// The states are numbered in breadth-first order, so keys doubles as
+	// the queue.
+	start := t.key(t.goalCounts, blankGoal)
+	t.index = map[uint64]int32{start: 0}
+	t.dist = []uint8{0}
+	keys := []uint64{start}
+	counts := make([]int, lines*lines)
+
+	for state := 0; state < len(keys); state++ {
+		blank := t.decode(keys[state], counts)
+
+		for _, next := range [2]int{blank - 1, blank + 1} {
+			for goal := 0; goal < lines; goal++ {
+				if next < 0 || next >= lines || counts[next*lines+goal] == 0 {
+					t.next = append(t.next, -1)
+					continue
+				}
+				counts[next*lines+goal]--
+				counts[blank*lines+goal]++
+				key := t.key(counts, next)
+				idx, ok := t.index[key]
+				if !ok {
+					if len(keys) == walkingMaxStates {
+						return nil, fmt.Errorf("walking distance of [%d] lines of [%d] cells has more than [%d] states", lines, length, walkingMaxStates)
+					}
+					idx = int32(len(keys))
+					t.index[key] = idx
+					t.dist = append(t.dist, t.dist[state]+1)
+					keys = append(keys, key)
+				}
+				t.next = append(t.next, idx)
+				counts[next*lines+goal]++
+				counts[blank*lines+goal]--
+			}
+		}
+	}
+	return t, nil
+}
+
+// move follows the blank going step lines from its own in state, taking the
+// place of a tile belonging to goal.
+func (t *walkingTable) move(state int32, step, goal int) int32 {
+	dir := 0
+	if step > 0 {
+		dir = 1
+	}
+	return t.next[(2*int(state)+dir)*t.lines+goal]
+}
+
+// shift is where the count of tiles in line belonging to goal sits in a key,
+// above the line of the blank.
+func (t *walkingTable) shift(line, goal int) uint {
+	return t.blankBits + t.countBits*uint(line*t.lines+goal)
+}
+
+func (t *walkingTable) key(counts []int, blank int) uint64 {
+	key := uint64(blank)
+	for line := 0; line < t.lines-1; line++ {
+		for goal := 0; goal < t.lines; goal++ {
+			key |= uint64(counts[line*t.lines+goal]) << t.shift(line, goal)
+		}
+	}
+	return key
+}
+
+// decode fills counts from key, the last line being whatever the goal counts
+// leave for it, and returns the line of the blank.
+func (t *walkingTable) decode(key uint64, counts []int) int {
+	mask := uint64(1)<<t.countBits - 1
+	for goal := 0; goal < t.lines; goal++ {
+		left := 0
+		for line := 0; line < t.lines; line++ {
+			left += t.goalCounts[line*t.lines+goal]
+		}
+		for line := 0; line < t.lines-1; line++ {
+			counts[line*t.lines+goal] = int(key >> t.shift(line, goal) & mask)
+			left -= counts[line*t.lines+goal]
+		}
+		counts[(t.lines-1)*t.lines+goal] = left
+	}
+	return int(key & (uint64(1)<<t.blankBits - 1))
+}
+
+// walkingDistanceHeuristic adds the walking distances between the rows and
+// between the columns, since every move goes along only one of them. With
+// linearConflict set it takes the maximum with the linear conflicts instead,
+// as the two cannot be added.
+type walkingDistanceHeuristic struct {
+	vertical       *walkingTable
+	horizontal     *walkingTable
+	linearConflict bool
+}
+
+func (p *PuzzleSolution) loadWalkingDistance(linearConflict bool) (heuristic, error) {
+	vertical, err := newWalkingTable(p.rows, p.cols, p.zeroIndex.x)
+	if err != nil {
+		return nil, err
+	}
+	horizontal := vertical
+	if p.rows != p.cols || p.zeroIndex.x != p.zeroIndex.y {
+		if horizontal, err = newWalkingTable(p.cols, p.rows, p.zeroIndex.y); err != nil {
+			return nil, err
+		}
+	}
+	return &walkingDistanceHeuristic{vertical: vertical, horizontal: horizontal, linearConflict: linearConflict}, nil
+}
+
+func (h *walkingDistanceHeuristic) Name() string {
+	if h.linearConflict {
+		return walkingDistanceLCName
+	}
+	return walkingDistanceName
+}
+
+// The aux values of a heuristicState hold the states of the board in the
+// vertical and horizontal tables, along with its linear conflicts when they
+// are used, so that a move only has to follow the table of the dimension it
+// is made along.
+const (
+	walkingVertical = iota
+	walkingHorizontal
+	walkingConflicts
+)
+
+// Compute builds both keys in one pass over the cells and looks up their
+// states.
+func (h *walkingDistanceHeuristic) Compute(p *PuzzleSolution) heuristicState {
+	vertical := uint64(p.currentZero.x)
+	horizontal := uint64(p.currentZero.y)
+	for idx := 0; idx < p.rows*p.cols; idx++ {
+		tile := p.board.get(idx)
+		if tile == 0 {
+			continue
+		}
+		x, y := idx/p.cols, idx%p.cols
+		shouldBe := p.goal[tile]
+		if x < p.rows-1 {
+			vertical += 1 << h.vertical.shift(x, shouldBe.x)
+		}
+		if y < p.cols-1 {
+			horizontal += 1 << h.horizontal.shift(y, shouldBe.y)
+		}
+	}
+
+	var state heuristicState
+	var ok bool
+	if state.aux[walkingVertical], ok = h.vertical.index[vertical]; !ok {
+		panic("walking distance: the board has no state in the vertical table")
+	}
+	if state.aux[walkingHorizontal], ok = h.horizontal.index[horizontal]; !ok {
+		panic("walking distance: the board has no state in the horizontal table")
+	}
+	if h.linearConflict {
+		state.aux[walkingConflicts] = int32(linearConflictHeuristic{}.Compute(p).value)
+	}
+	return h.withValue(state)
+}
+
+// Update follows the move in the table of the dimension it is made along,
+// starting from the states before it. The blank took the place of the tile,
+// so it went from `to` to `from`.
+func (h *walkingDistanceHeuristic) Update(p *PuzzleSolution, prev heuristicState, tile int, from, to coordinate) heuristicState {
+	state := prev
+	goal := p.goal[tile]
+	if from.x != to.x {
+		state.aux[walkingVertical] = h.vertical.move(prev.aux[walkingVertical], from.x-to.x, goal.x)
+	} else {
+		state.aux[walkingHorizontal] = h.horizontal.move(prev.aux[walkingHorizontal], from.y-to.y, goal.y)
+	}
+	if h.linearConflict {
+		conflicts := heuristicState{value: int(prev.aux[walkingConflicts])}
+		state.aux[walkingConflicts] = int32(linearConflictHeuristic{}.Update(p, conflicts, tile, from, to).value)
+	}
+	return h.withValue(state)
+}
+
+// withValue sets the estimate of state from its aux values.
+func (h *walkingDistanceHeuristic) withValue(state heuristicState) heuristicState {
+	state.value = int(h.vertical.dist[state.aux[walkingVertical]]) + int(h.horizontal.dist[state.aux[walkingHorizontal]])
+	if conflicts := int(state.aux[walkingConflicts]); h.linearConflict && conflicts > state.value {
+		state.value = conflicts
+	}
+	return state
+}